@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"log"
+	"sync"
 
 	"github.com/spf13/viper"
 )
@@ -36,3 +37,150 @@ func LoadConfig() (DatabaseConfig, error) {
 
 	return dbConfig, err
 }
+
+type AppConfig struct {
+	AppName      string             `mapstructure:"app_name"`
+	BaseURL      string             `mapstructure:"base_url"`
+	JWT          JWTConfig          `mapstructure:"jwt"`
+	Mail         MailConfig         `mapstructure:"mail"`
+	Verification VerificationConfig `mapstructure:"verification"`
+	WebAuthn     WebAuthnConfig     `mapstructure:"webauthn"`
+	Password     PasswordConfig     `mapstructure:"password"`
+	Email        EmailConfig        `mapstructure:"email"`
+	// FrontendURL is the web application opening the links sent by email; BaseURL when empty.
+	FrontendURL string `mapstructure:"frontend_url"`
+	// TrustedProxies are the addresses and ranges of the proxies allowed to report the client
+	// address in X-Forwarded-For. No proxy is trusted when empty.
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
+	// AdminEmails lists the operators allowed to use the administrative endpoints.
+	AdminEmails []string `mapstructure:"admin_emails"`
+	// OIDCProviders are the external identity providers users can sign in with.
+	OIDCProviders []OIDCProviderConfig `mapstructure:"oidc_providers"`
+}
+
+// JWTConfig holds the issuer, audience and keys used to sign and verify tokens.
+type JWTConfig struct {
+	Issuer   string             `mapstructure:"issuer"`
+	Audience string             `mapstructure:"audience"`
+	Keys     []SigningKeyConfig `mapstructure:"keys"`
+}
+
+// SigningKeyConfig describes one token signing key. Exactly one key should be active;
+// inactive keys are only used to verify tokens issued before their required retired_at time.
+type SigningKeyConfig struct {
+	Kid            string `mapstructure:"kid"`
+	Algorithm      string `mapstructure:"algorithm"`
+	PrivateKeyFile string `mapstructure:"private_key_file"`
+	Active         bool   `mapstructure:"active"`
+	RetiredAt      string `mapstructure:"retired_at"`
+}
+
+// MailConfig holds the SMTP settings for outgoing email. Without a host, emails are only logged.
+type MailConfig struct {
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	From     string `mapstructure:"from"`
+}
+
+// VerificationConfig decides which actions require a verified email address.
+type VerificationConfig struct {
+	RequireForOrgCreation      bool `mapstructure:"require_for_org_creation"`
+	RequireForInviteAcceptance bool `mapstructure:"require_for_invite_acceptance"`
+}
+
+// PasswordConfig selects how new password hashes are made. Hashes made with other settings
+// keep working and are upgraded when their user signs in.
+type PasswordConfig struct {
+	// Algorithm is "argon2id" (the default) or "bcrypt".
+	Algorithm  string               `mapstructure:"algorithm"`
+	Argon2     Argon2Config         `mapstructure:"argon2"`
+	BcryptCost int                  `mapstructure:"bcrypt_cost"`
+	Policy     PasswordPolicyConfig `mapstructure:"policy"`
+}
+
+// PasswordPolicyConfig decides which passwords users may choose. Zero values disable a rule,
+// except for the lengths which fall back to 8 and 128 characters.
+type PasswordPolicyConfig struct {
+	MinLength            int     `mapstructure:"min_length"`
+	MaxLength            int     `mapstructure:"max_length"`
+	MinCharacterClasses  int     `mapstructure:"min_character_classes"`
+	MinEntropyBits       float64 `mapstructure:"min_entropy_bits"`
+	DisallowPersonalInfo bool    `mapstructure:"disallow_personal_info"`
+	BreachedPasswordsDir string  `mapstructure:"breached_passwords_dir"`
+}
+
+// Argon2Config holds the argon2id cost parameters. Memory is in KiB.
+type Argon2Config struct {
+	Memory      uint32 `mapstructure:"memory"`
+	Iterations  uint32 `mapstructure:"iterations"`
+	Parallelism uint8  `mapstructure:"parallelism"`
+}
+
+// EmailConfig restricts the email addresses accounts may use.
+type EmailConfig struct {
+	BlockDisposableDomains bool `mapstructure:"block_disposable_domains"`
+	// DisposableDomainsFile lists one blocked domain per line; subdomains are blocked too.
+	DisposableDomainsFile string `mapstructure:"disposable_domains_file"`
+}
+
+// WebAuthnConfig identifies the relying party that passkeys are registered for.
+// When empty, the RP ID and origin are derived from the base URL.
+type WebAuthnConfig struct {
+	RPID    string   `mapstructure:"rp_id"`
+	RPName  string   `mapstructure:"rp_name"`
+	Origins []string `mapstructure:"origins"`
+}
+
+// OIDCProviderConfig describes an external OpenID Connect identity provider. Its endpoints
+// and keys are discovered from the issuer. Scopes default to openid, email and profile.
+type OIDCProviderConfig struct {
+	Name         string   `mapstructure:"name"`
+	Issuer       string   `mapstructure:"issuer"`
+	ClientID     string   `mapstructure:"client_id"`
+	ClientSecret string   `mapstructure:"client_secret"`
+	Scopes       []string `mapstructure:"scopes"`
+}
+
+var (
+	appConfig     AppConfig
+	appConfigOnce sync.Once
+)
+
+// GetAppConfig returns the application configuration, loading it on first use.
+func GetAppConfig() AppConfig {
+	appConfigOnce.Do(func() {
+		var err error
+		appConfig, err = LoadAppConfig()
+		if err != nil {
+			log.Fatalf("Unable to load app config: %v", err)
+		}
+	})
+	return appConfig
+}
+
+func LoadAppConfig() (AppConfig, error) {
+	v := viper.New()
+	v.AddConfigPath("./config")
+	v.SetConfigName("app-config")
+	v.SetConfigType("yaml")
+	// Enable VIPER to read Environment Variables
+	v.AutomaticEnv()
+
+	// Read the configurations file
+	err := v.ReadInConfig()
+	if err != nil {
+		fmt.Println(err)
+	}
+
+	var appConfig AppConfig
+
+	// Unmarshal the config into the AppConfig struct
+	err = v.Unmarshal(&appConfig)
+	if err != nil {
+		return appConfig, fmt.Errorf("unable to decode app config: %v", err)
+	}
+
+	return appConfig, nil
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"assessment/pkg/auth"
+	"assessment/pkg/database/mongodb/models"
+	"assessment/pkg/database/mongodb/repository"
+	"assessment/pkg/utils"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateAPIKey mints an API key bound to the organization from the URL.
+// The key itself is only returned in this response.
+func CreateAPIKey(c *gin.Context) {
+	var request models.APIKeyRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON payload"})
+		return
+	}
+
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "API key name is required"})
+		return
+	}
+	if err := validateAPIKeyPermissions(c, request.Permissions); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	allowedIPs, err := normalizeAllowedIPs(request.AllowedIPs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if request.ExpiresAt != nil && !request.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Expiry must be in the future"})
+		return
+	}
+
+	key, prefix, err := utils.GenerateAPIKey()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate API key"})
+		return
+	}
+
+	apiKey := models.APIKey{
+		OrganizationId: organizationFrom(c).Id,
+		Name:           name,
+		Prefix:         prefix,
+		KeyHash:        utils.HashToken(key),
+		Permissions:    request.Permissions,
+		AllowedIPs:     allowedIPs,
+		CreatedBy:      auth.PrincipalFrom(c).UserId,
+		CreatedAt:      time.Now(),
+		ExpiresAt:      request.ExpiresAt,
+	}
+	_, err = repository.NewAPIKeyRepo().CreateAPIKey(&apiKey)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIKeyResponse{
+		APIKey: apiKey,
+		Key:    key,
+	})
+}
+
+// GetAPIKeys lists the API keys of an organization.
+func GetAPIKeys(c *gin.Context) {
+	organizationID := c.Param("organization_id")
+
+	keys, err := repository.NewAPIKeyRepo().GetAPIKeysByOrganization(organizationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch API keys"})
+		return
+	}
+
+	c.JSON(http.StatusOK, keys)
+}
+
+// GetAPIKey retrieves one API key of an organization.
+func GetAPIKey(c *gin.Context) {
+	organizationID := c.Param("organization_id")
+	keyID := c.Param("key_id")
+
+	key, err := repository.NewAPIKeyRepo().GetAPIKey(organizationID, keyID)
+	if err != nil || key == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, key)
+}
+
+// UpdateAPIKey renames an API key or replaces its permissions or IP restrictions.
+func UpdateAPIKey(c *gin.Context) {
+	organizationID := c.Param("organization_id")
+	keyID := c.Param("key_id")
+	var request models.APIKeyUpdateRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON payload"})
+		return
+	}
+
+	request.Name = strings.TrimSpace(request.Name)
+	if request.Permissions != nil {
+		if err := validateAPIKeyPermissions(c, request.Permissions); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if request.AllowedIPs != nil {
+		allowedIPs, err := normalizeAllowedIPs(request.AllowedIPs)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		// Keep an empty list so the restriction is lifted rather than left unchanged.
+		request.AllowedIPs = append([]string{}, allowedIPs...)
+	}
+	if request.Name == "" && request.Permissions == nil && request.AllowedIPs == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Nothing to update"})
+		return
+	}
+
+	key, err := repository.NewAPIKeyRepo().UpdateAPIKey(organizationID, keyID, &request)
+	if err != nil || key == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, key)
+}
+
+// DeleteAPIKey revokes an API key of an organization.
+func DeleteAPIKey(c *gin.Context) {
+	organizationID := c.Param("organization_id")
+	keyID := c.Param("key_id")
+
+	deleted, err := repository.NewAPIKeyRepo().DeleteAPIKey(organizationID, keyID)
+	if err != nil || !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked"})
+}
+
+// validateAPIKeyPermissions checks that the permissions are known and granted by the caller's own role.
+func validateAPIKeyPermissions(c *gin.Context, permissions []string) error {
+	if err := validatePermissions(permissions); err != nil {
+		return err
+	}
+	return checkRoleGrants(c, permissions)
+}
+
+// normalizeAllowedIPs validates IP restrictions and returns them in canonical form.
+func normalizeAllowedIPs(entries []string) ([]string, error) {
+	var allowedIPs []string
+	for _, entry := range entries {
+		prefix, err := utils.ParseIPRestriction(strings.TrimSpace(entry))
+		if err != nil {
+			return nil, err
+		}
+		allowedIPs = append(allowedIPs, prefix.String())
+	}
+	return allowedIPs, nil
+}
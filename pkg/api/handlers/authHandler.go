@@ -1,11 +1,12 @@
 package handlers
 
 import (
-	"assessment/config"
 	"assessment/pkg/database/mongodb/models"
 	"assessment/pkg/database/mongodb/repository"
 	"assessment/pkg/utils"
+	"log"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -22,9 +23,11 @@ func Signup(c *gin.Context) {
 		return
 	}
 
+	user.Email = strings.TrimSpace(user.Email)
+
 	// Validate the user data before proceeding.
 	if err := utils.ValidateUser(user); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondValidationError(c, err)
 		return
 	}
 
@@ -36,23 +39,36 @@ func Signup(c *gin.Context) {
 	}
 	user.Password = hash
 
+	// New accounts start unverified.
+	user.EmailVerifiedAt = nil
+
 	// Attempt to create the user in the database.
 	createdUser, err := repo.CreateUser(&user)
+	if err == repository.ErrEmailExists {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email already exists"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "user not created"})
 		return
 	}
 
-	// Generate authentication tokens for the newly created user.
-	access_token, refresh_token, err := utils.GenerateTokens(createdUser.Name, createdUser.Email)
+	// Start a session and generate authentication tokens for the newly created user.
+	access_token, refresh_token, err := startSession(c, createdUser)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
+	// Ask the user to confirm their email address. A failure here is not fatal, the email can be resent.
+	err = sendVerificationEmail(createdUser.Id.Hex(), createdUser.Email)
+	if err != nil {
+		log.Printf("Failed to send verification email to %s: %v", createdUser.Email, err)
+	}
+
 	// Respond with success message and tokens.
 	c.JSON(http.StatusCreated, models.AuthResponse{
-		Message:      "User created successfully",
+		Message:      "User created successfully, please verify your email address",
 		AccessToken:  access_token,
 		RefreshToken: refresh_token,
 	})
@@ -76,22 +92,59 @@ func SignIn(c *gin.Context) {
 		return
 	}
 
+	// Refuse attempts while the email or client is backed off or locked out, before any password is compared.
+	block, err := utils.CheckLoginAllowed(credentials.Email, c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+		return
+	}
+	if block != nil {
+		respondLoginBlocked(c, block)
+		return
+	}
+
 	// Find the user by email in the database.
 	userFound, err := repo.FindUserByEmail(credentials.Email)
-	if err != nil {
+	if err != nil || userFound == nil {
+		recordFailedSignIn(c, credentials.Email, nil)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
 
+	// Members of organizations enforcing single sign-on must sign in with their identity provider.
+	// This is decided before the password is compared so the answer does not confirm a guessed password.
+	if refuseUnlessSSOAllowed(c, userFound, "") {
+		return
+	}
+
 	// Verify the provided password against the stored hash.
 	isMatch, err := utils.CheckPasswordHash(credentials.Password, userFound.Password)
 	if err != nil || !isMatch {
+		recordFailedSignIn(c, credentials.Email, userFound)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
 
-	// Generate authentication tokens for the authenticated user.
-	access_token, refresh_token, err := utils.GenerateTokens(userFound.Name, userFound.Email)
+	// Upgrade hashes made with an outdated algorithm or cost while the plaintext password is at hand.
+	if utils.PasswordNeedsRehash(userFound.Password) {
+		if err := rehashPassword(userFound, credentials.Password); err != nil {
+			log.Printf("Failed to upgrade password hash of %s: %v", userFound.Email, err)
+		}
+	}
+
+	// Users with two-factor authentication must present their second factor before receiving tokens.
+	if requireSecondFactor(c, userFound, models.AuthMethodPassword) {
+		return
+	}
+
+	// The sign-in is complete, so earlier failures no longer count towards a lockout. With a second
+	// factor this waits for VerifyMFA, so guessing codes cannot keep clearing the counter.
+	if err := utils.ResetLoginFailures(credentials.Email); err != nil {
+		log.Printf("Failed to reset sign-in failures of %s: %v", credentials.Email, err)
+	}
+
+	// Start a session and generate authentication tokens for the authenticated user.
+	access_token, refresh_token, err := startSession(c, userFound)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
@@ -105,7 +158,17 @@ func SignIn(c *gin.Context) {
 	})
 }
 
-// RefreshToken issues new access and refresh tokens using a valid refresh token.
+// rehashPassword stores a new hash of the user's password made with the configured hasher.
+func rehashPassword(user *models.User, password string) error {
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	return repository.NewUserRepo().RehashPassword(user.Id.Hex(), user.Password, hash)
+}
+
+// RefreshToken rotates a refresh token, issuing new access and refresh tokens in the same family.
 func RefreshToken(c *gin.Context) {
 	// Parse the incoming JSON payload containing the refresh token.
 	var request models.RefreshToken
@@ -115,19 +178,34 @@ func RefreshToken(c *gin.Context) {
 		return
 	}
 
-	// Verify the refresh token and extract the associated username and email.
-	username, email, err := utils.VerifyRefreshToken(request.Token)
-	if err != nil {
+	// Refresh tokens of OAuth clients are only accepted at the token endpoint, which authenticates the client.
+	claims, err := utils.Tokens().Parse(request.Token, utils.TokenTypeRefresh)
+	if err != nil || claims.ClientId != "" {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
 		return
 	}
 
-	// Generate new access and refresh tokens for the user.
-	accessToken, refreshToken, err := utils.GenerateTokens(username, email)
+	// Refuse to refresh tokens of a session that has been signed out.
+	sessionRepo := repository.NewSessionRepo()
+	session, err := sessionRepo.GetSessionById(claims.SessionId)
+	if err != nil || session == nil || session.RevokedAt != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has been revoked"})
+		return
+	}
+
+	// Consume the refresh token and generate new access and refresh tokens for the user.
+	accessToken, refreshToken, err := utils.RotateRefreshToken(request.Token)
+	if err == utils.ErrRefreshTokenReused {
+		// The whole family is gone, so the session it belonged to is over as well.
+		sessionRepo.RevokeSession(claims.SessionId)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token reuse detected, all tokens of this sign-in have been revoked"})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate tokens"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
 		return
 	}
+	sessionRepo.TouchSession(claims.SessionId)
 
 	// Prepare the response with the new tokens.
 	response := models.AuthResponse{
@@ -139,7 +217,7 @@ func RefreshToken(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
-// RevokeRefreshToken removes a refresh token from the system, effectively logging the user out.
+// RevokeRefreshToken revokes a refresh token together with its family, effectively logging the user out.
 func RevokeRefreshToken(c *gin.Context) {
 	// Parse the incoming JSON payload containing the refresh token to be revoked.
 	var requestBody *models.RefreshToken
@@ -149,12 +227,30 @@ func RevokeRefreshToken(c *gin.Context) {
 		return
 	}
 
-	// Delete the refresh token from Redis
-	err := config.Init_redis().Del(requestBody.Token).Err()
+	claims, err := utils.Tokens().Parse(requestBody.Token, utils.TokenTypeRefresh)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		return
+	}
+
+	// Delete the refresh token family from Redis
+	err = utils.RevokeRefreshFamily(claims.Subject, claims.Family)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke refresh token"})
 		return
 	}
 
+	// End the session so its access tokens stop working too.
+	err = repository.NewSessionRepo().RevokeSession(claims.SessionId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "Refresh token revoked"})
 }
+
+// GetJWKS publishes the public keys that verify the tokens issued by this API.
+func GetJWKS(c *gin.Context) {
+	c.JSON(http.StatusOK, utils.Tokens().JWKS())
+}
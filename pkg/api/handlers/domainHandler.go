@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"assessment/pkg/database/mongodb/models"
+	"assessment/pkg/database/mongodb/repository"
+	"assessment/pkg/utils"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetOrganizationDomains lists the email domains an organization claims with their verification records.
+func GetOrganizationDomains(c *gin.Context) {
+	domains := organizationFrom(c).Domains
+
+	response := make([]models.DomainResponse, 0, len(domains))
+	for _, domain := range domains {
+		response = append(response, domainResponse(domain))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// AddOrganizationDomain claims an email domain for an organization. The domain counts once its
+// ownership is proven by publishing the returned DNS TXT record and calling VerifyOrganizationDomain.
+func AddOrganizationDomain(c *gin.Context) {
+	organizationID := c.Param("organization_id")
+	var request models.DomainRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON payload"})
+		return
+	}
+
+	name, err := utils.NormalizeDomain(request.Domain)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid domain"})
+		return
+	}
+
+	token, err := utils.GenerateSecureToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add domain"})
+		return
+	}
+
+	domain := models.OrganizationDomain{
+		Name:              name,
+		VerificationToken: token,
+		CreatedAt:         time.Now(),
+	}
+	added, err := repository.NewOrganizationRepo().AddDomain(organizationID, &domain)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add domain"})
+		return
+	}
+	if !added {
+		c.JSON(http.StatusConflict, gin.H{"error": "Domain already added"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, domainResponse(domain))
+}
+
+// VerifyOrganizationDomain checks that a claimed domain publishes its verification record.
+// A domain can only be verified by one organization.
+func VerifyOrganizationDomain(c *gin.Context) {
+	organizationID := c.Param("organization_id")
+
+	domain := findDomain(organizationFrom(c), c.Param("domain"))
+	if domain == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Domain not found"})
+		return
+	}
+	if domain.VerifiedAt != nil {
+		c.JSON(http.StatusOK, domainResponse(*domain))
+		return
+	}
+
+	repo := repository.NewOrganizationRepo()
+	owner, err := repo.GetOrganizationByVerifiedDomain(domain.Name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify domain"})
+		return
+	}
+	if owner != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Domain is verified by another organization"})
+		return
+	}
+
+	verified, err := utils.VerifyDomainOwnership(domain.Name, domain.VerificationToken)
+	if err != nil {
+		log.Printf("Failed to look up verification record of %s: %v", domain.Name, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to look up the verification record"})
+		return
+	}
+	if !verified {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Verification record not found"})
+		return
+	}
+
+	now := time.Now()
+	if err := repo.MarkDomainVerified(organizationID, domain.Name, now); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify domain"})
+		return
+	}
+	domain.VerifiedAt = &now
+
+	c.JSON(http.StatusOK, domainResponse(*domain))
+}
+
+// DeleteOrganizationDomain withdraws the claim of an organization on a domain. Accounts already
+// linked to single sign-on stay linked.
+func DeleteOrganizationDomain(c *gin.Context) {
+	organizationID := c.Param("organization_id")
+
+	name, err := utils.NormalizeDomain(c.Param("domain"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Domain not found"})
+		return
+	}
+
+	deleted, err := repository.NewOrganizationRepo().DeleteDomain(organizationID, name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete domain"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Domain not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Domain deleted successfully"})
+}
+
+// ownsEmailDomain reports whether the organization verified the domain of a normalized email address.
+func ownsEmailDomain(organization *models.Organization, email string) bool {
+	domain := findDomain(organization, utils.EmailDomain(email))
+	return domain != nil && domain.VerifiedAt != nil
+}
+
+// findDomain returns the domain an organization claims by name, or nil when it claims no such domain.
+func findDomain(organization *models.Organization, name string) *models.OrganizationDomain {
+	name, err := utils.NormalizeDomain(name)
+	if err != nil {
+		return nil
+	}
+	for i := range organization.Domains {
+		if organization.Domains[i].Name == name {
+			return &organization.Domains[i]
+		}
+	}
+	return nil
+}
+
+// domainResponse adds the verification record to a claimed domain.
+func domainResponse(domain models.OrganizationDomain) models.DomainResponse {
+	name, value := utils.DomainVerificationRecord(domain.Name, domain.VerificationToken)
+	return models.DomainResponse{
+		OrganizationDomain: domain,
+		RecordName:         name,
+		RecordValue:        value,
+	}
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"assessment/pkg/auth"
+	"assessment/pkg/database/mongodb/models"
+	"assessment/pkg/database/mongodb/repository"
+	"assessment/pkg/utils"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ChangeEmail starts an email change for the signed-in user by sending a confirmation
+// link to the new address. The address is only swapped once the link is used.
+func ChangeEmail(c *gin.Context) {
+	var request models.ChangeEmailRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON payload"})
+		return
+	}
+
+	principal := auth.PrincipalFrom(c)
+	repo := repository.NewUserRepo()
+	user, err := repo.FindUserById(principal.UserId.Hex())
+	if err != nil || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	// Require the password so a stolen access token cannot take over the account.
+	isMatch, err := utils.CheckPasswordHash(request.Password, user.Password)
+	if err != nil || !isMatch {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Password is incorrect"})
+		return
+	}
+
+	if err := utils.ValidateEmail(request.NewEmail); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if request.NewEmail == user.Email {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "New email is the same as the current one"})
+		return
+	}
+
+	// Changing only the capitalization of the own address is allowed.
+	existing, err := repo.FindUserByEmail(request.NewEmail)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change email"})
+		return
+	}
+	if existing != nil && existing.Id != user.Id {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email already exists"})
+		return
+	}
+
+	token, err := utils.Tokens().IssueEmailChangeToken(user.Id.Hex(), user.Email, request.NewEmail, principal.SessionId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	link := emailLink("/confirm-email-change", token)
+	body := fmt.Sprintf("Please confirm that you want to use this address for your account by opening the link below.\n\n%s\n\nThe link expires in %s.\n", link, utils.EmailVerificationExpiry)
+	err = utils.Mail().Send(request.NewEmail, "Confirm your new email address", body)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send confirmation email"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Confirmation email sent to the new address"})
+}
+
+// ConfirmEmailChange swaps the email address of a user using the token sent to the new address.
+// Pending invitations follow the new address, every other session is signed out and personal
+// access tokens are revoked.
+func ConfirmEmailChange(c *gin.Context) {
+	var request models.ConfirmEmailChangeRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON payload"})
+		return
+	}
+
+	claims, err := utils.Tokens().Parse(request.Token, utils.TokenTypeEmailChange)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired token"})
+		return
+	}
+
+	// The token is only good while the account still uses the address it replaces.
+	repo := repository.NewUserRepo()
+	user, err := repo.FindUserById(claims.Subject)
+	if err != nil || user == nil || user.Email != claims.PreviousEmail {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired token"})
+		return
+	}
+
+	existing, err := repo.FindUserByEmail(claims.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change email"})
+		return
+	}
+	if existing != nil && existing.Id != user.Id {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email already exists"})
+		return
+	}
+
+	err = repo.UpdateEmail(user.Id.Hex(), claims.Email)
+	if err == repository.ErrEmailExists {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email already exists"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change email"})
+		return
+	}
+
+	// Memberships are keyed by user ID; pending invitations are keyed by email and must follow.
+	previousEmail, _ := utils.NormalizeEmail(claims.PreviousEmail)
+	newEmail, _ := utils.NormalizeEmail(claims.Email)
+	err = repository.NewInvitationRepo().UpdatePendingInvitationsEmail(previousEmail, newEmail)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update invitations"})
+		return
+	}
+
+	// As when changing the password, other sessions and personal access tokens do not survive the change.
+	err = revokeOtherSessions(user.Id.Hex(), claims.SessionId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+	err = repository.NewPersonalTokenRepo().DeleteTokensByUser(user.Id.Hex())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke access tokens"})
+		return
+	}
+
+	// Let the previous address know, in case the change was not made by its owner.
+	body := fmt.Sprintf("The email address of your account was changed to %s. If you did not make this change, reset your password immediately.\n", claims.Email)
+	if err := utils.Mail().Send(claims.PreviousEmail, "Your email address was changed", body); err != nil {
+		log.Printf("Failed to notify %s of email change: %v", claims.PreviousEmail, err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email changed successfully"})
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"assessment/config"
+	"assessment/pkg/auth"
+	"assessment/pkg/database/mongodb/models"
+	"assessment/pkg/database/mongodb/repository"
+	"assessment/pkg/utils"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AcceptInvitation joins the signed-in user to the organization of an invitation.
+func AcceptInvitation(c *gin.Context) {
+	if !requireVerifiedEmail(c, config.GetAppConfig().Verification.RequireForInviteAcceptance) {
+		return
+	}
+
+	invitation, principal, ok := resolveInvitation(c)
+	if !ok {
+		return
+	}
+
+	// Join first and consume the invitation after, so a failure leaves it pending for a retry.
+	// The unique membership index makes joining twice harmless.
+	membershipRepo := repository.NewMembershipRepo()
+	_, err := membershipRepo.CreateMembership(&models.Membership{
+		UserId:         principal.UserId,
+		OrganizationId: invitation.OrganizationId,
+		Role:           invitation.Role,
+		JoinedAt:       time.Now(),
+		InvitedBy:      invitation.InvitedBy,
+	})
+	joined := err == nil
+	if err != nil && !errors.Is(err, repository.ErrMembershipExists) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept invitation"})
+		return
+	}
+
+	repo := repository.NewInvitationRepo()
+	updated, err := repo.UpdateInvitationStatus(invitation.Id.Hex(), models.InvitationAccepted)
+	if err == nil && !updated {
+		// A concurrent request may have accepted it already; otherwise it was revoked or
+		// declined in the meantime and grants nothing.
+		current, err := repo.GetInvitationById(invitation.Id.Hex())
+		if err == nil && current != nil && current.Status == models.InvitationAccepted {
+			c.JSON(http.StatusConflict, gin.H{"error": "Invitation is no longer pending"})
+			return
+		}
+		if joined {
+			if _, err := membershipRepo.DeleteMembership(invitation.OrganizationId.Hex(), principal.UserId.Hex()); err != nil {
+				log.Printf("Failed to remove membership of %s joined through invitation %s: %v", principal.UserId.Hex(), invitation.Id.Hex(), err)
+			}
+		}
+		c.JSON(http.StatusConflict, gin.H{"error": "Invitation is no longer pending"})
+		return
+	}
+	if err != nil {
+		// The user joined; the invitation stays pending and accepting it again completes it.
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept invitation"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":         "Invitation accepted",
+		"organization_id": invitation.OrganizationId,
+		"role":            invitation.Role,
+	})
+}
+
+// DeclineInvitation rejects an invitation addressed to the signed-in user.
+func DeclineInvitation(c *gin.Context) {
+	invitation, _, ok := resolveInvitation(c)
+	if !ok {
+		return
+	}
+
+	updated, err := repository.NewInvitationRepo().UpdateInvitationStatus(invitation.Id.Hex(), models.InvitationDeclined)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decline invitation"})
+		return
+	}
+	if !updated {
+		c.JSON(http.StatusConflict, gin.H{"error": "Invitation is no longer pending"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation declined"})
+}
+
+// GetOrganizationInvitations lists the invitations of an organization.
+func GetOrganizationInvitations(c *gin.Context) {
+	organizationID := c.Param("organization_id")
+
+	repo := repository.NewInvitationRepo()
+
+	// Bring the status of lapsed invitations up to date before listing them.
+	err := repo.ExpireInvitations(organizationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invitations"})
+		return
+	}
+
+	invitations, err := repo.GetInvitationsByOrganization(organizationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invitations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, invitations)
+}
+
+// RevokeInvitation withdraws a pending invitation of an organization.
+func RevokeInvitation(c *gin.Context) {
+	organizationID := c.Param("organization_id")
+	invitationID := c.Param("invitation_id")
+
+	repo := repository.NewInvitationRepo()
+	invitation, err := repo.GetInvitationById(invitationID)
+	if err != nil || invitation == nil || invitation.OrganizationId.Hex() != organizationID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+		return
+	}
+
+	updated, err := repo.UpdateInvitationStatus(invitationID, models.InvitationRevoked)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke invitation"})
+		return
+	}
+	if !updated {
+		c.JSON(http.StatusConflict, gin.H{"error": "Invitation is no longer pending"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation revoked"})
+}
+
+// resolveInvitation loads the pending invitation behind the token in the URL and checks
+// that it is addressed to the signed-in user. It writes the error response itself.
+func resolveInvitation(c *gin.Context) (*models.Invitation, *auth.Principal, bool) {
+	claims, err := utils.Tokens().Parse(c.Param("token"), utils.TokenTypeInvite)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired invite token"})
+		return nil, nil, false
+	}
+	invitationID := claims.Subject
+
+	repo := repository.NewInvitationRepo()
+	invitation, err := repo.GetInvitationById(invitationID)
+	if err != nil || invitation == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+		return nil, nil, false
+	}
+
+	if invitation.Status == models.InvitationPending && time.Now().After(invitation.ExpiresAt) {
+		if _, err := repo.UpdateInvitationStatus(invitationID, models.InvitationExpired); err != nil {
+			log.Printf("Failed to mark invitation %s expired: %v", invitationID, err)
+		}
+		invitation.Status = models.InvitationExpired
+	}
+	if invitation.Status != models.InvitationPending {
+		c.JSON(http.StatusGone, gin.H{"error": "Invitation is " + invitation.Status})
+		return nil, nil, false
+	}
+
+	principal := auth.PrincipalFrom(c)
+	if !utils.EmailsEqual(principal.Email, invitation.Email) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Invitation was sent to a different email address"})
+		return nil, nil, false
+	}
+
+	return invitation, principal, true
+}
@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"assessment/config"
+	"assessment/pkg/database/mongodb/models"
+	"assessment/pkg/database/mongodb/repository"
+	"assessment/pkg/utils"
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Magic links that may be requested per email address within magicLinkRateWindow.
+const (
+	magicLinkRateLimit  = 5
+	magicLinkRateWindow = time.Hour
+)
+
+// magicLinkNonceCookie holds the device nonce on the browser that requested a magic link.
+const magicLinkNonceCookie = "magic_link_nonce"
+
+// RequestMagicLink emails a single-use sign-in link. The link is bound to a nonce returned to
+// (and set as a cookie on) the requesting device, so a link forwarded elsewhere does not work.
+// The response does not reveal whether an account exists for the email.
+func RequestMagicLink(c *gin.Context) {
+	var request models.MagicLinkRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON payload"})
+		return
+	}
+
+	// Limit the emails that can be sent to an address, whether or not it has an account. The
+	// limit is keyed on the normalized address, which every way of writing it shares. No account
+	// can exist for an address that does not normalize, so nothing is sent to it.
+	email, err := utils.NormalizeEmail(request.Email)
+	validEmail := err == nil
+	if validEmail {
+		allowed, retryAfter, err := utils.AllowRequest("magic_link:"+email, magicLinkRateLimit, magicLinkRateWindow)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send sign-in link"})
+			return
+		}
+		if !allowed {
+			c.Header("Retry-After", strconv.Itoa(int(retryAfter.Seconds())))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many sign-in links requested, please try again later"})
+			return
+		}
+	}
+
+	nonce, err := utils.GenerateSecureToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send sign-in link"})
+		return
+	}
+
+	// Look the account up and send in the background, so the response time does not reveal
+	// whether an account exists.
+	if validEmail {
+		go func() {
+			user, err := repository.NewUserRepo().FindUserByEmail(email)
+			if err != nil {
+				log.Printf("Failed to look up user for magic link: %v", err)
+			}
+			if user != nil {
+				if err := sendMagicLinkEmail(user, nonce); err != nil {
+					log.Printf("Failed to send magic link to %s: %v", user.Email, err)
+				}
+			}
+		}()
+	}
+
+	secure := strings.HasPrefix(config.GetAppConfig().BaseURL, "https://")
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(magicLinkNonceCookie, nonce, int(utils.MagicLinkExpiry.Seconds()), "/auth/magic-link", "", secure, true)
+
+	c.JSON(http.StatusAccepted, models.MagicLinkResponse{
+		Message:     "If an account exists for this email, a sign-in link has been sent",
+		DeviceNonce: nonce,
+	})
+}
+
+// ConsumeMagicLink signs a user in with a magic link. The device nonce is taken from the
+// request or, for links opened in the requesting browser, from the cookie set by RequestMagicLink.
+func ConsumeMagicLink(c *gin.Context) {
+	var request models.ConsumeMagicLinkRequest
+
+	if err := c.ShouldBind(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	claims, err := utils.Tokens().Parse(request.Token, utils.TokenTypeMagicLink)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired sign-in link"})
+		return
+	}
+
+	// The link only works on the device that requested it.
+	nonce := request.Nonce
+	if nonce == "" {
+		nonce, _ = c.Cookie(magicLinkNonceCookie)
+	}
+	if nonce == "" || subtle.ConstantTimeCompare([]byte(utils.HashToken(nonce)), []byte(claims.NonceHash)) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Sign-in link must be opened on the device that requested it"})
+		return
+	}
+
+	used, err := utils.ConsumeMagicLink(claims.Id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+		return
+	}
+	if !used {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired sign-in link"})
+		return
+	}
+
+	// The link only signs in while the account still uses the address it was sent to.
+	repo := repository.NewUserRepo()
+	user, err := repo.FindUserById(claims.Subject)
+	if err != nil || user == nil || user.Email != claims.Email {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired sign-in link"})
+		return
+	}
+
+	// Receiving the link proves ownership of the address.
+	if user.EmailVerifiedAt == nil {
+		if err := repo.MarkEmailVerified(user.Id.Hex()); err != nil {
+			log.Printf("Failed to mark email of %s verified: %v", user.Email, err)
+		}
+	}
+
+	c.SetCookie(magicLinkNonceCookie, "", -1, "/auth/magic-link", "", false, true)
+
+	if refuseUnlessSSOAllowed(c, user, "") {
+		return
+	}
+
+	// Users with two-factor authentication must present their second factor before receiving tokens.
+	if requireSecondFactor(c, user, models.AuthMethodEmailLink) {
+		return
+	}
+
+	access_token, refresh_token, err := startSession(c, user, models.AuthMethodEmailLink)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.AuthResponse{
+		Message:      "SignIn successful",
+		AccessToken:  access_token,
+		RefreshToken: refresh_token,
+	})
+}
+
+// sendMagicLinkEmail issues a magic link bound to the device nonce and emails it to the user.
+func sendMagicLinkEmail(user *models.User, nonce string) error {
+	token, tokenID, err := utils.Tokens().IssueMagicLinkToken(user.Id.Hex(), user.Email, utils.HashToken(nonce))
+	if err != nil {
+		return err
+	}
+
+	err = utils.StoreMagicLink(tokenID)
+	if err != nil {
+		return err
+	}
+
+	link := config.GetAppConfig().BaseURL + "/auth/magic-link/consume?token=" + url.QueryEscape(token)
+	body := fmt.Sprintf("Open the link below on the device where you requested it to sign in.\n\n%s\n\nThe link expires in %s and can be used once. If you did not request this, you can ignore this email.\n", link, utils.MagicLinkExpiry)
+
+	return utils.Mail().Send(user.Email, "Your sign-in link", body)
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"assessment/pkg/auth"
+	"assessment/pkg/database/mongodb/models"
+	"assessment/pkg/database/mongodb/repository"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GetOrganizationMembers lists the members of an organization with their roles.
+func GetOrganizationMembers(c *gin.Context) {
+	organizationID := c.Param("organization_id")
+
+	memberships, err := repository.NewMembershipRepo().GetMembershipsByOrganization(organizationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch members"})
+		return
+	}
+
+	userIDs := make([]primitive.ObjectID, 0, len(memberships))
+	for _, membership := range memberships {
+		userIDs = append(userIDs, membership.UserId)
+	}
+	users, err := repository.NewUserRepo().FindUsersByIds(userIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch members"})
+		return
+	}
+	usersByID := make(map[primitive.ObjectID]*models.User, len(users))
+	for _, user := range users {
+		usersByID[user.Id] = user
+	}
+
+	members := make([]models.MemberResponse, 0, len(memberships))
+	for _, membership := range memberships {
+		member := models.MemberResponse{
+			UserId:   membership.UserId,
+			Role:     membership.Role,
+			JoinedAt: membership.JoinedAt,
+		}
+		if user := usersByID[membership.UserId]; user != nil {
+			member.Name, member.Email = user.Name, user.Email
+		}
+		members = append(members, member)
+	}
+
+	c.JSON(http.StatusOK, members)
+}
+
+// RemoveOrganizationMember removes a user from an organization. The last owner cannot be removed.
+func RemoveOrganizationMember(c *gin.Context) {
+	organizationID := c.Param("organization_id")
+	userID := c.Param("user_id")
+
+	membership, ok := managedMembership(c, organizationID, userID, "Failed to remove member")
+	if !ok {
+		return
+	}
+	if !keepsAnOwner(c, organizationID, membership, "Failed to remove member") {
+		return
+	}
+
+	removed, err := repository.NewMembershipRepo().DeleteMembership(organizationID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove member"})
+		return
+	}
+	if !removed {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed"})
+}
+
+// UpdateOrganizationMember changes the role of a member. Like invitations, nobody can hand out
+// or take away rights they do not hold, and the last owner cannot be demoted.
+func UpdateOrganizationMember(c *gin.Context) {
+	organizationID := c.Param("organization_id")
+	userID := c.Param("user_id")
+	var request models.MemberRoleUpdate
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON payload"})
+		return
+	}
+
+	role := auth.FindRole(organizationFrom(c), request.Role)
+	if role == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role"})
+		return
+	}
+	if err := checkRoleGrants(c, role.Permissions); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	membership, ok := managedMembership(c, organizationID, userID, "Failed to update member")
+	if !ok {
+		return
+	}
+	if role.Name != models.RoleOwner && !keepsAnOwner(c, organizationID, membership, "Failed to update member") {
+		return
+	}
+
+	updated, err := repository.NewMembershipRepo().UpdateMembershipRole(organizationID, userID, role.Name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update member"})
+		return
+	}
+	if !updated {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member updated"})
+}
+
+// managedMembership loads the membership of a user the caller wants to change, or responds with
+// 404 or 403. Members holding rights the caller lacks cannot be demoted or removed by them.
+func managedMembership(c *gin.Context, organizationID, userID, failure string) (*models.Membership, bool) {
+	membership, err := repository.NewMembershipRepo().GetMembership(organizationID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": failure})
+		return nil, false
+	}
+	if membership == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return nil, false
+	}
+
+	// A role deleted since it was assigned grants nothing.
+	var permissions []string
+	if role := auth.FindRole(organizationFrom(c), membership.Role); role != nil {
+		permissions = role.Permissions
+	}
+	if checkRoleGrants(c, permissions) != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Member has rights you do not hold"})
+		return nil, false
+	}
+
+	return membership, true
+}
+
+// keepsAnOwner responds with 409 and reports false when the membership is the last owner of the
+// organization, which nobody could manage without one.
+func keepsAnOwner(c *gin.Context, organizationID string, membership *models.Membership, failure string) bool {
+	if membership.Role != models.RoleOwner {
+		return true
+	}
+
+	owners, err := repository.NewMembershipRepo().CountMembershipsByRole(organizationID, models.RoleOwner)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": failure})
+		return false
+	}
+	if owners <= 1 {
+		c.JSON(http.StatusConflict, gin.H{"error": "An organization needs at least one owner"})
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"assessment/config"
+	"assessment/pkg/auth"
+	"assessment/pkg/database/mongodb/models"
+	"assessment/pkg/database/mongodb/repository"
+	"assessment/pkg/utils"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// recoveryCodeCount is the number of recovery codes handed out when two-factor authentication is enabled.
+const recoveryCodeCount = 10
+
+// SetupTOTP starts enrollment of an authenticator app by generating a new secret for the user.
+// The secret only takes effect once a code generated from it is confirmed with ConfirmTOTP.
+func SetupTOTP(c *gin.Context) {
+	principal := auth.PrincipalFrom(c)
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate secret"})
+		return
+	}
+
+	stored, err := repository.NewUserRepo().SetPendingTOTPSecret(principal.UserId.Hex(), secret)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store secret"})
+		return
+	}
+	if !stored {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.TOTPSetupResponse{
+		Secret:     secret,
+		OtpauthURI: utils.TOTPURI(config.GetAppConfig().AppName, principal.Email, secret),
+	})
+}
+
+// ConfirmTOTP enables two-factor authentication once the user proves their authenticator
+// produces valid codes, and returns the one-time recovery codes.
+func ConfirmTOTP(c *gin.Context) {
+	var request models.TOTPCodeRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON payload"})
+		return
+	}
+
+	principal := auth.PrincipalFrom(c)
+	repo := repository.NewUserRepo()
+	user, err := repo.FindUserById(principal.UserId.Hex())
+	if err != nil || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	if user.TOTP == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication setup has not been started"})
+		return
+	}
+	if user.TOTP.Enabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+
+	step, valid := utils.ValidateTOTP(user.TOTP.Secret, strings.TrimSpace(request.Code), time.Now())
+	if !valid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid code"})
+		return
+	}
+
+	// Only hashes of the recovery codes are stored; the user sees them this one time.
+	recoveryCodes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate recovery codes"})
+		return
+	}
+	hashes := make([]string, 0, len(recoveryCodes))
+	for _, code := range recoveryCodes {
+		hashes = append(hashes, utils.HashToken(code))
+	}
+
+	err = repo.EnableTOTP(user.Id.Hex(), step, hashes)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.RecoveryCodesResponse{
+		Message:       "Two-factor authentication enabled, store these recovery codes somewhere safe",
+		RecoveryCodes: recoveryCodes,
+	})
+}
+
+// DisableTOTP turns off two-factor authentication. It requires the password and a current code
+// or recovery code, so a stolen access token alone cannot remove the second factor.
+func DisableTOTP(c *gin.Context) {
+	var request models.DisableTOTPRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON payload"})
+		return
+	}
+
+	principal := auth.PrincipalFrom(c)
+	repo := repository.NewUserRepo()
+	user, err := repo.FindUserById(principal.UserId.Hex())
+	if err != nil || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	if user.TOTP == nil || !user.TOTP.Enabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+
+	isMatch, err := utils.CheckPasswordHash(request.Password, user.Password)
+	if err != nil || !isMatch {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Password is incorrect"})
+		return
+	}
+
+	valid, err := verifySecondFactor(user, request.Code, request.RecoveryCode)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify code"})
+		return
+	}
+	if !valid {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid code"})
+		return
+	}
+
+	err = repo.DisableTOTP(user.Id.Hex())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// VerifyMFA completes a two-step sign-in by exchanging an MFA challenge token and a code
+// or recovery code for access and refresh tokens.
+func VerifyMFA(c *gin.Context) {
+	var request models.MFAVerifyRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON payload"})
+		return
+	}
+
+	claims, err := utils.Tokens().Parse(request.MFAToken, utils.TokenTypeMFAChallenge)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired MFA token"})
+		return
+	}
+
+	// Limit the codes that can be guessed with a single challenge.
+	err = utils.RecordMFAAttempt(claims.Id)
+	if err == utils.ErrMFAChallengeExhausted || err == utils.ErrMFAChallengeCompleted {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "MFA token is no longer valid, please sign in again"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify code"})
+		return
+	}
+
+	user, err := repository.NewUserRepo().FindUserById(claims.Subject)
+	if err != nil || user == nil || user.TOTP == nil || !user.TOTP.Enabled {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired MFA token"})
+		return
+	}
+
+	// Wrong codes count towards the same lockout as wrong passwords, across challenges.
+	block, err := utils.CheckLoginAllowed(user.Email, c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify code"})
+		return
+	}
+	if block != nil {
+		respondLoginBlocked(c, block)
+		return
+	}
+
+	valid, err := verifySecondFactor(user, request.Code, request.RecoveryCode)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify code"})
+		return
+	}
+	if !valid {
+		recordFailedSignIn(c, user.Email, user)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid code"})
+		return
+	}
+
+	if err := utils.ResetLoginFailures(user.Email); err != nil {
+		log.Printf("Failed to reset sign-in failures of %s: %v", user.Email, err)
+	}
+
+	// A challenge is exchanged for tokens at most once.
+	if err := utils.CompleteMFAChallenge(claims.Id); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "MFA token is no longer valid, please sign in again"})
+		return
+	}
+
+	authMethods := append(claims.AuthMethods, models.AuthMethodOTP)
+	access_token, refresh_token, err := startSession(c, user, authMethods...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.AuthResponse{
+		Message:      "SignIn successful",
+		AccessToken:  access_token,
+		RefreshToken: refresh_token,
+	})
+}
+
+// requireSecondFactor answers a sign-in with an MFA challenge when the user has two-factor
+// authentication enabled, and reports whether it did. authMethods are the methods the user
+// already passed and are carried over to the session created by VerifyMFA.
+func requireSecondFactor(c *gin.Context, user *models.User, authMethods ...string) bool {
+	if user.TOTP == nil || !user.TOTP.Enabled {
+		return false
+	}
+
+	mfaToken, err := utils.Tokens().IssueMFAChallengeToken(user.Id.Hex(), authMethods)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return true
+	}
+
+	c.JSON(http.StatusOK, models.MFAChallengeResponse{
+		Message:     "Two-factor authentication required",
+		MFARequired: true,
+		MFAToken:    mfaToken,
+	})
+	return true
+}
+
+// verifySecondFactor checks an authenticator code or, when no code is given, a recovery code.
+// Accepted codes are used up: a code's time step cannot be replayed and a recovery code is removed.
+func verifySecondFactor(user *models.User, code, recoveryCode string) (bool, error) {
+	repo := repository.NewUserRepo()
+
+	code = strings.TrimSpace(code)
+	if code != "" {
+		step, valid := utils.ValidateTOTP(user.TOTP.Secret, code, time.Now())
+		if !valid {
+			return false, nil
+		}
+		return repo.RecordTOTPStep(user.Id.Hex(), step)
+	}
+
+	recoveryCode = strings.ToLower(strings.TrimSpace(recoveryCode))
+	if recoveryCode != "" {
+		return repo.ConsumeRecoveryCode(user.Id.Hex(), utils.HashToken(recoveryCode))
+	}
+
+	return false, nil
+}
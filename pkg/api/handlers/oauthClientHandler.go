@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"assessment/pkg/auth"
+	"assessment/pkg/database/mongodb/models"
+	"assessment/pkg/database/mongodb/repository"
+	"assessment/pkg/utils"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateOAuthClient registers an OAuth client owned by the organization from the URL.
+// The secret of a confidential client is only returned in this response.
+func CreateOAuthClient(c *gin.Context) {
+	var request models.OAuthClientRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON payload"})
+		return
+	}
+
+	client := models.OAuthClient{
+		OrganizationId: organizationFrom(c).Id,
+		Name:           strings.TrimSpace(request.Name),
+		Public:         request.Public,
+		RedirectURIs:   request.RedirectURIs,
+		GrantTypes:     request.GrantTypes,
+		Scopes:         request.Scopes,
+		CreatedBy:      auth.PrincipalFrom(c).UserId,
+		CreatedAt:      time.Now(),
+	}
+	if client.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Client name is required"})
+		return
+	}
+	if err := validateOAuthClient(c, &client); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Public clients cannot keep a secret, so they get none and must rely on PKCE.
+	var secret string
+	if !client.Public {
+		var err error
+		secret, err = utils.GenerateSecureToken()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate client secret"})
+			return
+		}
+		client.SecretHash = utils.HashToken(secret)
+	}
+
+	_, err := repository.NewOAuthClientRepo().CreateClient(&client)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create client"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.OAuthClientResponse{
+		OAuthClient:  client,
+		ClientSecret: secret,
+	})
+}
+
+// GetOAuthClients lists the OAuth clients of an organization.
+func GetOAuthClients(c *gin.Context) {
+	organizationID := c.Param("organization_id")
+
+	clients, err := repository.NewOAuthClientRepo().GetClientsByOrganization(organizationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch clients"})
+		return
+	}
+
+	c.JSON(http.StatusOK, clients)
+}
+
+// GetOAuthClient retrieves one OAuth client of an organization.
+func GetOAuthClient(c *gin.Context) {
+	organizationID := c.Param("organization_id")
+	clientID := c.Param("client_id")
+
+	client, err := repository.NewOAuthClientRepo().GetClient(organizationID, clientID)
+	if err != nil || client == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Client not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, client)
+}
+
+// UpdateOAuthClient renames an OAuth client or replaces its redirect URIs or scopes.
+// Tokens already issued keep the scopes they were granted.
+func UpdateOAuthClient(c *gin.Context) {
+	organizationID := c.Param("organization_id")
+	clientID := c.Param("client_id")
+	var request models.OAuthClientUpdateRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON payload"})
+		return
+	}
+
+	request.Name = strings.TrimSpace(request.Name)
+	if request.Name == "" && request.RedirectURIs == nil && request.Scopes == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Nothing to update"})
+		return
+	}
+
+	repo := repository.NewOAuthClientRepo()
+	client, err := repo.GetClient(organizationID, clientID)
+	if err != nil || client == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Client not found"})
+		return
+	}
+
+	// Validate the client as it will be after the update.
+	if request.RedirectURIs != nil {
+		client.RedirectURIs = request.RedirectURIs
+	}
+	if request.Scopes != nil {
+		client.Scopes = request.Scopes
+	}
+	if err := validateOAuthClient(c, client); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	client, err = repo.UpdateClient(organizationID, clientID, &request)
+	if err != nil || client == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Client not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, client)
+}
+
+// DeleteOAuthClient removes an OAuth client of an organization and ends the sessions users authorized for it.
+func DeleteOAuthClient(c *gin.Context) {
+	organizationID := c.Param("organization_id")
+	clientID := c.Param("client_id")
+
+	deleted, err := repository.NewOAuthClientRepo().DeleteClient(organizationID, clientID)
+	if err != nil || !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Client not found"})
+		return
+	}
+
+	// Tokens of a deleted client are refused anyway; this also tidies up the users' session lists.
+	if err := repository.NewSessionRepo().RevokeClientSessions(clientID); err != nil {
+		log.Printf("Failed to revoke sessions of OAuth client %s: %v", clientID, err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Client deleted"})
+}
+
+// validateOAuthClient checks the grant types, redirect URIs and scopes of a client. A client
+// using client credentials acts for the organization, so its permissions must be granted by
+// the caller's own role.
+func validateOAuthClient(c *gin.Context, client *models.OAuthClient) error {
+	if len(client.GrantTypes) == 0 {
+		return errors.New("At least one grant type is required")
+	}
+	for _, grantType := range client.GrantTypes {
+		switch grantType {
+		case models.GrantTypeAuthorizationCode:
+			if len(client.RedirectURIs) == 0 {
+				return errors.New("At least one redirect URI is required for the authorization code grant")
+			}
+		case models.GrantTypeClientCredentials:
+			if client.Public {
+				return errors.New("Public clients cannot use the client credentials grant")
+			}
+		default:
+			return fmt.Errorf("Unsupported grant type %s", grantType)
+		}
+	}
+
+	if err := validateRedirectURIs(client.RedirectURIs); err != nil {
+		return err
+	}
+	if len(client.Scopes) == 0 {
+		return errors.New("At least one scope is required")
+	}
+	for _, scope := range client.Scopes {
+		if !auth.IsValidClientScope(scope) {
+			return fmt.Errorf("Unknown scope %s", scope)
+		}
+	}
+
+	if client.AllowsGrant(models.GrantTypeClientCredentials) {
+		var permissions []string
+		for _, scope := range client.Scopes {
+			if auth.IsValidPermission(scope) {
+				permissions = append(permissions, scope)
+			}
+		}
+		return checkRoleGrants(c, permissions)
+	}
+	return nil
+}
+
+// validateRedirectURIs checks that redirect URIs are absolute and have no fragment. Plain http is
+// only accepted for loopback addresses and custom schemes must look like a reverse domain name,
+// as used by native apps (RFC 8252).
+func validateRedirectURIs(uris []string) error {
+	for _, raw := range uris {
+		uri, err := url.Parse(raw)
+		if err != nil || !uri.IsAbs() || uri.Fragment != "" {
+			return fmt.Errorf("Invalid redirect URI %s", raw)
+		}
+
+		switch uri.Scheme {
+		case "https":
+			if uri.Host == "" {
+				return fmt.Errorf("Invalid redirect URI %s", raw)
+			}
+		case "http":
+			host := uri.Hostname()
+			ip := net.ParseIP(host)
+			if host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+				return fmt.Errorf("Redirect URI %s must use https", raw)
+			}
+		default:
+			if !strings.Contains(uri.Scheme, ".") {
+				return fmt.Errorf("Redirect URI %s has an unsupported scheme", raw)
+			}
+		}
+	}
+	return nil
+}
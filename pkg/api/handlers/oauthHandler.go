@@ -0,0 +1,391 @@
+package handlers
+
+import (
+	"assessment/pkg/auth"
+	"assessment/pkg/database/mongodb/models"
+	"assessment/pkg/database/mongodb/repository"
+	"assessment/pkg/utils"
+	"crypto/subtle"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAuthorization validates an authorization request of an OAuth client and describes what
+// the signed-in user is asked to approve, so the frontend can show a consent screen.
+func GetAuthorization(c *gin.Context) {
+	var request models.AuthorizationRequest
+
+	if err := c.ShouldBindQuery(&request); err != nil {
+		oauthError(c, http.StatusBadRequest, "invalid_request", "Malformed authorization request")
+		return
+	}
+
+	client, scopes, ok := validateAuthorizationRequest(c, &request)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ConsentResponse{
+		ClientId:    client.Id.Hex(),
+		ClientName:  client.Name,
+		Scopes:      scopes,
+		RedirectURI: effectiveRedirectURI(client, request.RedirectURI),
+	})
+}
+
+// Authorize records the user's decision on an authorization request. Approval issues a
+// single-use authorization code; either way the response says where to send the user back to.
+func Authorize(c *gin.Context) {
+	var request models.AuthorizationRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		oauthError(c, http.StatusBadRequest, "invalid_request", "Malformed authorization request")
+		return
+	}
+
+	client, scopes, ok := validateAuthorizationRequest(c, &request)
+	if !ok {
+		return
+	}
+	redirectURI := effectiveRedirectURI(client, request.RedirectURI)
+
+	if !request.Approve {
+		c.JSON(http.StatusOK, models.AuthorizationResponse{
+			RedirectTo: authorizationRedirect(redirectURI, request.State, url.Values{
+				"error":             {"access_denied"},
+				"error_description": {"The user denied the request"},
+			}),
+		})
+		return
+	}
+
+	code, err := utils.GenerateSecureToken()
+	if err != nil {
+		oauthError(c, http.StatusInternalServerError, "server_error", "Failed to issue authorization code")
+		return
+	}
+
+	// The code carries the user's consent and the PKCE challenge to the token endpoint.
+	principal := auth.PrincipalFrom(c)
+	grant := &utils.AuthorizationCode{
+		ClientId:      client.Id.Hex(),
+		UserId:        principal.UserId.Hex(),
+		RedirectURI:   request.RedirectURI,
+		Scopes:        scopes,
+		CodeChallenge: request.CodeChallenge,
+		AuthMethods:   principal.AuthMethods,
+		Nonce:         request.Nonce,
+	}
+	// The user authenticated when the consenting session started.
+	if session, err := repository.NewSessionRepo().GetSessionById(principal.SessionId); err == nil && session != nil {
+		grant.AuthTime = session.CreatedAt.Unix()
+	}
+	err = utils.StoreAuthorizationCode(code, grant)
+	if err != nil {
+		oauthError(c, http.StatusInternalServerError, "server_error", "Failed to issue authorization code")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.AuthorizationResponse{
+		RedirectTo: authorizationRedirect(redirectURI, request.State, url.Values{"code": {code}}),
+	})
+}
+
+// OAuthToken is the token endpoint. It authenticates the client and serves the authorization_code,
+// refresh_token and client_credentials grants with responses as defined by RFC 6749.
+func OAuthToken(c *gin.Context) {
+	// Token responses must never be cached.
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	var request models.TokenRequest
+	if err := c.ShouldBind(&request); err != nil {
+		oauthError(c, http.StatusBadRequest, "invalid_request", "Malformed token request")
+		return
+	}
+
+	client, ok := authenticateOAuthClient(c, &request)
+	if !ok {
+		return
+	}
+
+	switch request.GrantType {
+	case models.GrantTypeAuthorizationCode, models.GrantTypeRefreshToken, models.GrantTypeClientCredentials:
+		if !client.AllowsGrant(request.GrantType) {
+			oauthError(c, http.StatusBadRequest, "unauthorized_client", "The client may not use this grant type")
+			return
+		}
+	default:
+		oauthError(c, http.StatusBadRequest, "unsupported_grant_type", "Unsupported grant type")
+		return
+	}
+
+	switch request.GrantType {
+	case models.GrantTypeAuthorizationCode:
+		exchangeAuthorizationCode(c, client, &request)
+	case models.GrantTypeRefreshToken:
+		refreshOAuthToken(c, client, &request)
+	case models.GrantTypeClientCredentials:
+		issueClientCredentialsToken(c, client, &request)
+	}
+}
+
+// exchangeAuthorizationCode redeems an authorization code for tokens of a new session of the user.
+func exchangeAuthorizationCode(c *gin.Context, client *models.OAuthClient, request *models.TokenRequest) {
+	grant, err := utils.ConsumeAuthorizationCode(request.Code)
+	if err != nil {
+		oauthError(c, http.StatusInternalServerError, "server_error", "Failed to redeem authorization code")
+		return
+	}
+
+	// The code only works for the client it was issued to, with the same redirect URI and the PKCE verifier.
+	if grant == nil || grant.ClientId != client.Id.Hex() || grant.RedirectURI != request.RedirectURI {
+		oauthError(c, http.StatusBadRequest, "invalid_grant", "Invalid or expired authorization code")
+		return
+	}
+	if !utils.VerifyPKCE(request.CodeVerifier, grant.CodeChallenge) {
+		oauthError(c, http.StatusBadRequest, "invalid_grant", "Code verifier does not match the code challenge")
+		return
+	}
+
+	user, err := repository.NewUserRepo().FindUserById(grant.UserId)
+	if err != nil || user == nil {
+		oauthError(c, http.StatusBadRequest, "invalid_grant", "Invalid or expired authorization code")
+		return
+	}
+
+	accessToken, refreshToken, err := startClientSession(c, user, client, grant.Scopes, grant.AuthMethods)
+	if err != nil {
+		oauthError(c, http.StatusInternalServerError, "server_error", "Failed to generate token")
+		return
+	}
+
+	response := models.TokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(utils.AccessTokenExpiry.Seconds()),
+		RefreshToken: refreshToken,
+		Scope:        utils.FormatScope(grant.Scopes),
+	}
+
+	// OpenID Connect clients also learn who the user is.
+	if containsString(grant.Scopes, auth.ScopeOpenID) {
+		response.IDToken, err = issueIDToken(user, client.Id.Hex(), grant.Scopes, grant.AuthMethods, grant.Nonce, grant.AuthTime)
+		if err != nil {
+			oauthError(c, http.StatusInternalServerError, "server_error", "Failed to generate ID token")
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// refreshOAuthToken rotates a refresh token of the client, keeping the scopes originally granted.
+func refreshOAuthToken(c *gin.Context, client *models.OAuthClient, request *models.TokenRequest) {
+	claims, err := utils.Tokens().Parse(request.RefreshToken, utils.TokenTypeRefresh)
+	if err != nil || claims.ClientId != client.Id.Hex() {
+		oauthError(c, http.StatusBadRequest, "invalid_grant", "Invalid refresh token")
+		return
+	}
+
+	// The user may have revoked the client's access from their session list.
+	sessionRepo := repository.NewSessionRepo()
+	session, err := sessionRepo.GetSessionById(claims.SessionId)
+	if err != nil || session == nil || session.RevokedAt != nil {
+		oauthError(c, http.StatusBadRequest, "invalid_grant", "Authorization has been revoked")
+		return
+	}
+
+	accessToken, refreshToken, err := utils.RotateRefreshToken(request.RefreshToken)
+	if err == utils.ErrRefreshTokenReused {
+		sessionRepo.RevokeSession(claims.SessionId)
+		oauthError(c, http.StatusBadRequest, "invalid_grant", "Refresh token reuse detected, the authorization has been revoked")
+		return
+	}
+	if err != nil {
+		oauthError(c, http.StatusBadRequest, "invalid_grant", "Invalid refresh token")
+		return
+	}
+	sessionRepo.TouchSession(claims.SessionId)
+
+	response := models.TokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(utils.AccessTokenExpiry.Seconds()),
+		RefreshToken: refreshToken,
+		Scope:        claims.Scope,
+	}
+
+	// A fresh ID token reflects changes to the user since the last one.
+	scopes := utils.ParseScope(claims.Scope)
+	if containsString(scopes, auth.ScopeOpenID) {
+		user, err := repository.NewUserRepo().FindUserById(claims.Subject)
+		if err != nil || user == nil {
+			oauthError(c, http.StatusBadRequest, "invalid_grant", "Invalid refresh token")
+			return
+		}
+		response.IDToken, err = issueIDToken(user, client.Id.Hex(), scopes, session.AuthMethods, "", 0)
+		if err != nil {
+			oauthError(c, http.StatusInternalServerError, "server_error", "Failed to generate ID token")
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// issueClientCredentialsToken issues an access token through which the client acts for its own
+// organization with the permissions among its scopes.
+func issueClientCredentialsToken(c *gin.Context, client *models.OAuthClient, request *models.TokenRequest) {
+	scopes := utils.ParseScope(request.Scope)
+	if len(scopes) == 0 {
+		for _, scope := range client.Scopes {
+			if auth.IsValidPermission(scope) {
+				scopes = append(scopes, scope)
+			}
+		}
+	}
+	for _, scope := range scopes {
+		if !auth.IsValidPermission(scope) || !containsString(client.Scopes, scope) {
+			oauthError(c, http.StatusBadRequest, "invalid_scope", "Scope "+scope+" is not available to the client")
+			return
+		}
+	}
+
+	accessToken, err := utils.Tokens().IssueClientCredentialsToken(client.Id.Hex(), client.OrganizationId.Hex(), scopes)
+	if err != nil {
+		oauthError(c, http.StatusInternalServerError, "server_error", "Failed to generate token")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.TokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(utils.AccessTokenExpiry.Seconds()),
+		Scope:       utils.FormatScope(scopes),
+	})
+}
+
+// validateAuthorizationRequest checks the client, redirect URI, PKCE challenge and scopes of an
+// authorization request and returns the client with the scopes to grant. On failure it responds and
+// reports false; errors are only sent back to the client once its redirect URI is known to be genuine.
+func validateAuthorizationRequest(c *gin.Context, request *models.AuthorizationRequest) (*models.OAuthClient, []string, bool) {
+	client, err := repository.NewOAuthClientRepo().GetClientById(request.ClientId)
+	if err != nil || client == nil {
+		oauthError(c, http.StatusBadRequest, "invalid_request", "Unknown client")
+		return nil, nil, false
+	}
+
+	redirectURI := effectiveRedirectURI(client, request.RedirectURI)
+	if redirectURI == "" || !client.AllowsRedirectURI(redirectURI) {
+		oauthError(c, http.StatusBadRequest, "invalid_request", "Redirect URI is not registered for the client")
+		return nil, nil, false
+	}
+
+	if request.ResponseType != "code" {
+		oauthRedirectError(c, redirectURI, request.State, "unsupported_response_type", "Only the code response type is supported")
+		return nil, nil, false
+	}
+	if !client.AllowsGrant(models.GrantTypeAuthorizationCode) {
+		oauthRedirectError(c, redirectURI, request.State, "unauthorized_client", "The client may not use the authorization code grant")
+		return nil, nil, false
+	}
+	if request.CodeChallenge == "" || request.CodeChallengeMethod != "S256" {
+		oauthRedirectError(c, redirectURI, request.State, "invalid_request", "PKCE with the S256 method is required")
+		return nil, nil, false
+	}
+
+	// Without an explicit scope the client gets everything it is registered for.
+	scopes := utils.ParseScope(request.Scope)
+	if len(scopes) == 0 {
+		scopes = client.Scopes
+	}
+	for _, scope := range scopes {
+		if !containsString(client.Scopes, scope) {
+			oauthRedirectError(c, redirectURI, request.State, "invalid_scope", "Scope "+scope+" is not available to the client")
+			return nil, nil, false
+		}
+	}
+
+	return client, scopes, true
+}
+
+// authenticateOAuthClient identifies the client calling the token endpoint from HTTP Basic
+// credentials or the client_id and client_secret parameters. Public clients only send their ID.
+func authenticateOAuthClient(c *gin.Context, request *models.TokenRequest) (*models.OAuthClient, bool) {
+	clientID, secret, basic := c.Request.BasicAuth()
+	if basic {
+		// Basic credentials are form-encoded before they are combined (RFC 6749 section 2.3.1).
+		clientID, _ = url.QueryUnescape(clientID)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		clientID, secret = request.ClientId, request.ClientSecret
+	}
+
+	client, err := repository.NewOAuthClientRepo().GetClientById(clientID)
+	authenticated := err == nil && client != nil
+	if authenticated && !client.Public {
+		authenticated = secret != "" && subtle.ConstantTimeCompare([]byte(utils.HashToken(secret)), []byte(client.SecretHash)) == 1
+	}
+	if !authenticated {
+		if basic {
+			c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+		}
+		oauthError(c, http.StatusUnauthorized, "invalid_client", "Client authentication failed")
+		return nil, false
+	}
+
+	return client, true
+}
+
+// effectiveRedirectURI returns the redirect URI of a request, defaulting to the only one registered.
+func effectiveRedirectURI(client *models.OAuthClient, redirectURI string) string {
+	if redirectURI == "" && len(client.RedirectURIs) == 1 {
+		return client.RedirectURIs[0]
+	}
+	return redirectURI
+}
+
+// authorizationRedirect adds the response parameters and state to a registered redirect URI.
+func authorizationRedirect(redirectURI, state string, params url.Values) string {
+	target, _ := url.Parse(redirectURI)
+	query := target.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	if state != "" {
+		query.Set("state", state)
+	}
+	target.RawQuery = query.Encode()
+	return target.String()
+}
+
+// oauthError responds with an error in the format defined by RFC 6749.
+func oauthError(c *gin.Context, status int, code, description string) {
+	c.JSON(status, gin.H{"error": code, "error_description": description})
+}
+
+// oauthRedirectError responds with an authorization error together with the redirect that
+// reports it to the client.
+func oauthRedirectError(c *gin.Context, redirectURI, state, code, description string) {
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error":             code,
+		"error_description": description,
+		"redirect_to": authorizationRedirect(redirectURI, state, url.Values{
+			"error":             {code},
+			"error_description": {description},
+		}),
+	})
+}
+
+// containsString reports whether value is present in values.
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"assessment/config"
+	"assessment/pkg/auth"
+	"assessment/pkg/database/mongodb/models"
+	"assessment/pkg/database/mongodb/repository"
+	"assessment/pkg/utils"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GetOpenIDConfiguration publishes the OpenID Connect provider metadata.
+func GetOpenIDConfiguration(c *gin.Context) {
+	issuer := openIDIssuer()
+
+	c.JSON(http.StatusOK, models.OpenIDConfiguration{
+		Issuer:                            issuer,
+		AuthorizationEndpoint:             issuer + "/oauth/authorize",
+		TokenEndpoint:                     issuer + "/oauth/token",
+		UserinfoEndpoint:                  issuer + "/userinfo",
+		JWKSURI:                           issuer + "/.well-known/jwks.json",
+		ScopesSupported:                   append(append([]string{}, auth.OpenIDScopes...), auth.AllScopes...),
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{models.GrantTypeAuthorizationCode, models.GrantTypeRefreshToken, models.GrantTypeClientCredentials},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{utils.Tokens().SigningAlgorithm()},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{"S256"},
+		ClaimsSupported:                   []string{"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "amr", "email", "email_verified", "name", "orgs"},
+	})
+}
+
+// GetUserInfo returns the claims about the signed-in user released for the scopes of the access token.
+func GetUserInfo(c *gin.Context) {
+	principal := auth.PrincipalFrom(c)
+
+	user, err := repository.NewUserRepo().FindUserById(principal.UserId.Hex())
+	if err != nil || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	claims, err := openIDClaims(user, principal.Scopes)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user info"})
+		return
+	}
+
+	c.JSON(http.StatusOK, claims)
+}
+
+// issueIDToken creates the ID token for a client the user authorized with the openid scope.
+// nonce and authTime are left out when empty.
+func issueIDToken(user *models.User, clientID string, scopes, authMethods []string, nonce string, authTime int64) (string, error) {
+	claims, err := openIDClaims(user, scopes)
+	if err != nil {
+		return "", err
+	}
+	if nonce != "" {
+		claims["nonce"] = nonce
+	}
+	if authTime != 0 {
+		claims["auth_time"] = authTime
+	}
+	if len(authMethods) > 0 {
+		claims["amr"] = authMethods
+	}
+
+	return utils.Tokens().IssueIDToken(openIDIssuer(), user.Id.Hex(), clientID, claims)
+}
+
+// openIDClaims collects the standard claims about a user released for the granted scopes.
+// Nil scopes, as for a first-party session, release every claim.
+func openIDClaims(user *models.User, scopes []string) (map[string]interface{}, error) {
+	granted := func(scope string) bool {
+		return scopes == nil || containsString(scopes, scope)
+	}
+
+	claims := map[string]interface{}{"sub": user.Id.Hex()}
+	if granted(auth.ScopeProfile) {
+		claims["name"] = user.Name
+	}
+	if granted(auth.ScopeEmail) {
+		claims["email"] = user.Email
+		claims["email_verified"] = user.EmailVerifiedAt != nil
+	}
+	if granted(auth.ScopeOrgs) {
+		orgs, err := organizationClaims(user.Id.Hex())
+		if err != nil {
+			return nil, err
+		}
+		claims["orgs"] = orgs
+	}
+
+	return claims, nil
+}
+
+// organizationClaims lists the organizations a user belongs to with their role in each.
+func organizationClaims(userID string) ([]models.OrganizationClaim, error) {
+	memberships, err := repository.NewMembershipRepo().GetMembershipsByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	organizationIDs := make([]primitive.ObjectID, 0, len(memberships))
+	for _, membership := range memberships {
+		organizationIDs = append(organizationIDs, membership.OrganizationId)
+	}
+	organizations, err := repository.NewOrganizationRepo().GetOrganizationsByIds(organizationIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	names := map[primitive.ObjectID]string{}
+	for _, organization := range organizations {
+		names[organization.Id] = organization.Name
+	}
+
+	orgs := []models.OrganizationClaim{}
+	for _, membership := range memberships {
+		name, exists := names[membership.OrganizationId]
+		if !exists {
+			continue
+		}
+		orgs = append(orgs, models.OrganizationClaim{
+			Id:   membership.OrganizationId.Hex(),
+			Name: name,
+			Role: membership.Role,
+		})
+	}
+	return orgs, nil
+}
+
+// openIDIssuer is the issuer identifier of the OpenID Connect provider, the public URL of the application.
+func openIDIssuer() string {
+	return strings.TrimSuffix(config.GetAppConfig().BaseURL, "/")
+}
@@ -0,0 +1,280 @@
+package handlers
+
+import (
+	"assessment/config"
+	"assessment/pkg/database/mongodb/models"
+	"assessment/pkg/database/mongodb/repository"
+	"assessment/pkg/utils"
+	"crypto/subtle"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// oidcStateCookie holds the state of a sign-in with an identity provider on the browser that started it.
+const oidcStateCookie = "oidc_state"
+
+// Reasons a sign-in with an identity provider cannot be tied to a local account.
+var (
+	errExternalEmailMissing  = errors.New("The identity provider did not share an email address")
+	errExternalAccountExists = errors.New("An account with this email address already exists and cannot be linked automatically")
+	errExternalSignupRefused = errors.New("No account exists for this email address")
+)
+
+// externalAccount is the account of a user at an external identity provider.
+type externalAccount struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// BeginOIDCLogin sends the user to an external identity provider to sign in. The sign-in is
+// bound to this browser by a state cookie and protected with PKCE and a nonce.
+func BeginOIDCLogin(c *gin.Context) {
+	provider := utils.GetOIDCProvider(c.Param("provider"))
+	if provider == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Identity provider not found"})
+		return
+	}
+
+	state, err := utils.GenerateSecureToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start sign-in"})
+		return
+	}
+	nonce, err := utils.GenerateSecureToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start sign-in"})
+		return
+	}
+	verifier, challenge, err := utils.NewPKCE()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start sign-in"})
+		return
+	}
+
+	authURL, err := provider.AuthCodeURL(state, nonce, challenge)
+	if err != nil {
+		log.Printf("Failed to reach identity provider %s: %v", provider.Name, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Identity provider is unavailable"})
+		return
+	}
+
+	err = utils.StoreOIDCLogin(state, &utils.OIDCLogin{
+		Provider:     provider.Name,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start sign-in"})
+		return
+	}
+
+	// The identity provider sends the user back with a top-level GET, which Lax cookies survive.
+	secure := strings.HasPrefix(config.GetAppConfig().BaseURL, "https://")
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, state, int(utils.OIDCLoginExpiry.Seconds()), "/auth/oidc", "", secure, true)
+
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback completes a sign-in with an external identity provider. The user of a linked
+// identity is signed in; otherwise the identity is linked to the account with the same
+// verified email address, or a new account is created for it.
+func OIDCCallback(c *gin.Context) {
+	provider := utils.GetOIDCProvider(c.Param("provider"))
+	if provider == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Identity provider not found"})
+		return
+	}
+
+	if c.Query("error") != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Sign-in was refused by the identity provider"})
+		return
+	}
+
+	// The callback only completes the sign-in started in this browser.
+	state := c.Query("state")
+	code := c.Query("code")
+	cookieState, _ := c.Cookie(oidcStateCookie)
+	if state == "" || code == "" || subtle.ConstantTimeCompare([]byte(state), []byte(cookieState)) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired sign-in"})
+		return
+	}
+
+	login, err := utils.ConsumeOIDCLogin(state)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+		return
+	}
+	if login == nil || login.Provider != provider.Name {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired sign-in"})
+		return
+	}
+
+	c.SetCookie(oidcStateCookie, "", -1, "/auth/oidc", "", false, true)
+
+	identity, err := provider.Exchange(code, login.CodeVerifier, login.Nonce)
+	if err != nil {
+		log.Printf("Failed to sign in with identity provider %s: %v", provider.Name, err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Failed to sign in with the identity provider"})
+		return
+	}
+
+	// Both the provider and the account must have verified the email address, so that neither an
+	// unverified provider address nor an account pre-registered with someone else's address is taken over.
+	account := externalAccount{
+		Provider:      provider.Name,
+		Subject:       identity.Subject,
+		Email:         identity.Email,
+		EmailVerified: identity.EmailVerified,
+		Name:          identity.Name,
+	}
+	user, err := resolveExternalUser(account, func(user *models.User) (bool, error) {
+		return identity.EmailVerified && user.EmailVerifiedAt != nil, nil
+	}, true)
+	if err == errExternalEmailMissing {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err == errExternalAccountExists {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to resolve user of identity provider %s: %v", provider.Name, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+		return
+	}
+
+	if refuseUnlessSSOAllowed(c, user, "") {
+		return
+	}
+
+	// Users with two-factor authentication must present their second factor before receiving tokens.
+	if requireSecondFactor(c, user, models.AuthMethodFederated) {
+		return
+	}
+
+	access_token, refresh_token, err := startSession(c, user, models.AuthMethodFederated)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.AuthResponse{
+		Message:      "SignIn successful",
+		AccessToken:  access_token,
+		RefreshToken: refresh_token,
+	})
+}
+
+// resolveExternalUser returns the local user of an account at a provider. On the first sign-in
+// the account is linked to the user with the same email address when canLink allows it, or a
+// new user is created when there is none and canCreate is set.
+func resolveExternalUser(account externalAccount, canLink func(*models.User) (bool, error), canCreate bool) (*models.User, error) {
+	identityRepo := repository.NewExternalIdentityRepo()
+	userRepo := repository.NewUserRepo()
+
+	link, err := identityRepo.GetIdentity(account.Provider, account.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if link != nil {
+		user, err := userRepo.FindUserById(link.UserId.Hex())
+		if err != nil {
+			return nil, err
+		}
+		if user == nil {
+			return nil, errors.New("linked user not found")
+		}
+		if err := identityRepo.RecordLogin(link.Id, account.Email); err != nil {
+			log.Printf("Failed to record sign-in of %s identity %s: %v", account.Provider, account.Subject, err)
+		}
+		return user, nil
+	}
+
+	if account.Email == "" {
+		return nil, errExternalEmailMissing
+	}
+
+	user, err := userRepo.FindUserByEmail(account.Email)
+	if err != nil {
+		return nil, err
+	}
+	if user != nil {
+		linkable, err := canLink(user)
+		if err != nil {
+			return nil, err
+		}
+		if !linkable {
+			return nil, errExternalAccountExists
+		}
+	} else if !canCreate {
+		return nil, errExternalSignupRefused
+	} else {
+		user, err = createExternalUser(account)
+		if err == repository.ErrEmailExists {
+			return nil, errExternalAccountExists
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	err = identityRepo.CreateIdentity(&models.ExternalIdentity{
+		UserId:      user.Id,
+		Provider:    account.Provider,
+		Subject:     account.Subject,
+		Email:       account.Email,
+		CreatedAt:   now,
+		LastLoginAt: now,
+	})
+	if err == repository.ErrIdentityExists {
+		// A concurrent sign-in linked the identity first.
+		return resolveExternalUser(account, canLink, canCreate)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// createExternalUser creates a user without a password for an account at an identity provider.
+// The email address counts as verified when the provider says so.
+func createExternalUser(account externalAccount) (*models.User, error) {
+	email := strings.TrimSpace(account.Email)
+	name := strings.TrimSpace(account.Name)
+	if name == "" {
+		name = strings.SplitN(email, "@", 2)[0]
+	}
+
+	user := &models.User{
+		Name:  name,
+		Email: email,
+	}
+	if account.EmailVerified {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+
+	createdUser, err := repository.NewUserRepo().CreateUser(user)
+	if err != nil {
+		return nil, err
+	}
+
+	if createdUser.EmailVerifiedAt == nil {
+		if err := sendVerificationEmail(createdUser.Id.Hex(), createdUser.Email); err != nil {
+			log.Printf("Failed to send verification email to %s: %v", createdUser.Email, err)
+		}
+	}
+	return createdUser, nil
+}
@@ -1,29 +1,64 @@
 package handlers
 
 import (
+	"assessment/config"
+	"assessment/pkg/auth"
 	"assessment/pkg/database/mongodb/models"
 	"assessment/pkg/database/mongodb/repository"
-	"fmt"
+	"assessment/pkg/utils"
+	"log"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-// CreateOrganization creates a new organization record.
+// CreateOrganization creates a new organization record and makes the caller its owner.
 func CreateOrganization(c *gin.Context) {
 	var org models.Organization
 	repo := repository.NewOrganizationRepo()
 
+	if !requireVerifiedEmail(c, config.GetAppConfig().Verification.RequireForOrgCreation) {
+		return
+	}
+
 	err := c.ShouldBindJSON(&org)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON payload"})
 		return
 	}
+	// Custom roles are managed through their own endpoints.
+	org.Roles = nil
+
+	// Record who created the organization.
+	principal := auth.PrincipalFrom(c)
+	org.CreatedBy = principal.UserId
+	org.CreatedAt = time.Now()
+
 	orgID, err := repo.CreateOrganization(&org)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create organization"})
 		return
 	}
+
+	// Record the caller as the owner of the new organization.
+	orgObjectID, _ := primitive.ObjectIDFromHex(orgID)
+	_, err = repository.NewMembershipRepo().CreateMembership(&models.Membership{
+		UserId:         principal.UserId,
+		OrganizationId: orgObjectID,
+		Role:           models.RoleOwner,
+		JoinedAt:       org.CreatedAt,
+	})
+	if err != nil {
+		// Do not leave an organization behind that nobody can manage.
+		if err := repo.DeleteOrganization(orgID); err != nil {
+			log.Printf("Failed to remove organization %s left without an owner: %v", orgID, err)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create organization"})
+		return
+	}
+
 	// Return a success message
 	c.JSON(http.StatusCreated, gin.H{"organization_id": orgID})
 }
@@ -47,10 +82,34 @@ func GetOrganizationById(c *gin.Context) {
 	})
 }
 
-// GetAllOrganizations lists all organizations.
+// GetAllOrganizations lists the organizations the caller is a member of, or the organization of an API key.
 func GetAllOrganizations(c *gin.Context) {
+	principal := auth.PrincipalFrom(c)
 	repo := repository.NewOrganizationRepo()
-	organizations, err := repo.GetAllOrganizations()
+
+	// An API key only ever sees the organization it belongs to.
+	if principal.IsService() {
+		organizations, err := repo.GetOrganizationsByIds([]primitive.ObjectID{principal.OrganizationId})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch organizations"})
+			return
+		}
+		c.JSON(http.StatusOK, organizations)
+		return
+	}
+
+	memberships, err := repository.NewMembershipRepo().GetMembershipsByUser(principal.UserId.Hex())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch organizations"})
+		return
+	}
+
+	organizationIDs := make([]primitive.ObjectID, 0, len(memberships))
+	for _, membership := range memberships {
+		organizationIDs = append(organizationIDs, membership.OrganizationId)
+	}
+
+	organizations, err := repo.GetOrganizationsByIds(organizationIDs)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch organizations"})
 		return
@@ -69,7 +128,11 @@ func UpdateOrganization(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	fmt.Println("req body", updateData)
+	if updateData.Name == "" && updateData.Description == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Nothing to update"})
+		return
+	}
+
 	repo := repository.NewOrganizationRepo()
 
 	organization, err := repo.UpdateOrganization(organizationID, &updateData)
@@ -86,7 +149,46 @@ func UpdateOrganization(c *gin.Context) {
 	})
 }
 
-// DeleteOrganization removes an organization from the database.
+// UpdateSecuritySettings changes the security settings of an organization that are provided.
+func UpdateSecuritySettings(c *gin.Context) {
+	organizationID := c.Param("organization_id")
+
+	var request models.SecuritySettingsUpdate
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON payload"})
+		return
+	}
+	if request.RequireMFA == nil && request.EnforceSSO == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Nothing to update"})
+		return
+	}
+
+	// Requiring two-factor authentication must not lock out the member turning it on.
+	if request.RequireMFA != nil && *request.RequireMFA && !auth.PrincipalFrom(c).HasMultiFactor() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Enable two-factor authentication before requiring it for the organization"})
+		return
+	}
+
+	// Enforcing single sign-on without an identity provider would lock every member out.
+	if request.EnforceSSO != nil && *request.EnforceSSO && organizationFrom(c).SAML == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Configure SAML single sign-on before enforcing it"})
+		return
+	}
+
+	organization, err := repository.NewOrganizationRepo().UpdateSecuritySettings(organizationID, &request)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update security settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SecuritySettingsResponse{
+		RequireMFA: organization.RequireMFA,
+		EnforceSSO: organization.EnforceSSO,
+	})
+}
+
+// DeleteOrganization removes an organization with its memberships, invitations, API keys, OAuth clients
+// and single sign-on identities from the database.
 func DeleteOrganization(c *gin.Context) {
 	organizationID := c.Param("organization_id")
 
@@ -97,11 +199,42 @@ func DeleteOrganization(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete organization"})
 		return
 	}
+
+	err = repository.NewMembershipRepo().DeleteMembershipsByOrganization(organizationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete organization memberships"})
+		return
+	}
+
+	err = repository.NewInvitationRepo().DeleteInvitationsByOrganization(organizationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete organization invitations"})
+		return
+	}
+
+	err = repository.NewAPIKeyRepo().DeleteAPIKeysByOrganization(organizationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete organization API keys"})
+		return
+	}
+
+	err = repository.NewOAuthClientRepo().DeleteClientsByOrganization(organizationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete organization OAuth clients"})
+		return
+	}
+
+	err = repository.NewExternalIdentityRepo().DeleteIdentitiesByProvider(samlProvider(organizationID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete organization single sign-on identities"})
+		return
+	}
+
 	// Return a success message
 	c.JSON(http.StatusOK, gin.H{"message": "Organization deleted successfully"})
 }
 
-// InviteUserToOrganization sends an invitation to join an organization.
+// InviteUserToOrganization creates a pending invitation for an email to join an organization.
 func InviteUserToOrganization(c *gin.Context) {
 	organizationID := c.Param("organization_id")
 	var requestBody models.InviterequestBody
@@ -111,14 +244,191 @@ func InviteUserToOrganization(c *gin.Context) {
 		return
 	}
 
-	repo := repository.NewOrganizationRepo()
-	// Call the InviteUserToOrganization method in the repository
-	err := repo.InviteUserToOrganization(organizationID, requestBody.UserEmail)
+	// Default to a regular member; ownership cannot be handed out through invitations.
+	if requestBody.Role == "" {
+		requestBody.Role = models.RoleMember
+	}
+	role := auth.FindRole(organizationFrom(c), requestBody.Role)
+	if role == nil || role.Name == models.RoleOwner {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role"})
+		return
+	}
+	// Nor can anyone invite into a role with rights they do not hold.
+	if err := checkRoleGrants(c, role.Permissions); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Invitations are addressed to the normalized email so they match however the invitee writes it.
+	email, err := utils.NormalizeEmail(requestBody.UserEmail)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid email"})
+		return
+	}
+	requestBody.UserEmail = email
+
+	// Refuse to invite someone who already belongs to the organization.
+	invitee, err := repository.NewUserRepo().FindUserByEmail(requestBody.UserEmail)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to invite user to organization"})
 		return
 	}
+	if invitee != nil {
+		existing, err := repository.NewMembershipRepo().GetMembership(organizationID, invitee.Id.Hex())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to invite user to organization"})
+			return
+		}
+		if existing != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "User is already a member of the organization"})
+			return
+		}
+	}
 
-	// Return a success message
-	c.JSON(http.StatusOK, gin.H{"message": "User invited to organization"})
+	repo := repository.NewInvitationRepo()
+	pending, err := repo.GetPendingInvitation(organizationID, requestBody.UserEmail)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to invite user to organization"})
+		return
+	}
+	if pending != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "User already has a pending invitation"})
+		return
+	}
+
+	// Record who sent the invitation, which for an integration is its API key or OAuth client.
+	principal := auth.PrincipalFrom(c)
+	now := time.Now()
+	invitation := models.Invitation{
+		OrganizationId: organizationFrom(c).Id,
+		Email:          requestBody.UserEmail,
+		Role:           requestBody.Role,
+		Status:         models.InvitationPending,
+		InvitedBy:      principal.UserId,
+		CreatedAt:      now,
+		ExpiresAt:      now.Add(utils.InviteTokenExpiry),
+	}
+	if principal.APIKeyId != "" {
+		keyID, _ := primitive.ObjectIDFromHex(principal.APIKeyId)
+		invitation.InvitedByAPIKey = &keyID
+	}
+	if principal.ClientId != "" {
+		invitation.InvitedByClient = principal.ClientId
+	}
+	invitationID, err := repo.CreateInvitation(&invitation)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to invite user to organization"})
+		return
+	}
+
+	inviteToken, err := utils.Tokens().IssueInviteToken(invitationID, invitation.Email, invitation.ExpiresAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate invite token"})
+		return
+	}
+
+	// Return the invite token so it can be delivered to the invitee.
+	c.JSON(http.StatusCreated, models.InvitationResponse{
+		InvitationId: invitationID,
+		InviteToken:  inviteToken,
+		ExpiresAt:    invitation.ExpiresAt,
+	})
+}
+
+// GetOrganizationsWithoutOwner lets an administrator list the organizations nobody can manage.
+func GetOrganizationsWithoutOwner(c *gin.Context) {
+	organizations, err := repository.NewOrganizationRepo().GetOrganizationsWithoutOwner()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch organizations"})
+		return
+	}
+	if organizations == nil {
+		organizations = []*models.Organization{}
+	}
+
+	c.JSON(http.StatusOK, organizations)
+}
+
+// AssignOrganizationOwner lets an administrator make a user the owner of an organization, for
+// organizations left without one. A member is promoted; anyone else joins as owner.
+func AssignOrganizationOwner(c *gin.Context) {
+	organizationID := c.Param("organization_id")
+	var request models.AssignOwnerRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON payload"})
+		return
+	}
+
+	organization, err := repository.NewOrganizationRepo().GetOrganizationById(organizationID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	}
+	user, err := repository.NewUserRepo().FindUserByEmail(request.UserEmail)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign owner"})
+		return
+	}
+	if user == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	repo := repository.NewMembershipRepo()
+	membership, err := repo.GetMembership(organizationID, user.Id.Hex())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign owner"})
+		return
+	}
+	if membership != nil {
+		_, err = repo.UpdateMembershipRole(organizationID, user.Id.Hex(), models.RoleOwner)
+	} else {
+		_, err = repo.CreateMembership(&models.Membership{
+			UserId:         user.Id,
+			OrganizationId: organization.Id,
+			Role:           models.RoleOwner,
+			JoinedAt:       time.Now(),
+		})
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign owner"})
+		return
+	}
+
+	actorID := auth.PrincipalFrom(c).UserId
+	event := &models.SecurityEvent{
+		Type:           models.SecurityEventOwnerAssigned,
+		UserId:         &user.Id,
+		Email:          user.Email,
+		IP:             c.ClientIP(),
+		ActorId:        &actorID,
+		OrganizationId: &organization.Id,
+		CreatedAt:      time.Now(),
+	}
+	if err := repository.NewSecurityEventRepo().CreateEvent(event); err != nil {
+		log.Printf("Failed to record owner assignment of organization %s: %v", organizationID, err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Owner assigned"})
+}
+
+// organizationFrom returns the organization resolved by RequirePermission.
+func organizationFrom(c *gin.Context) *models.Organization {
+	value, exists := c.Get("organization")
+	if !exists {
+		return nil
+	}
+	organization, _ := value.(*models.Organization)
+	return organization
+}
+
+// membershipFrom returns the caller's membership resolved by RequirePermission, or nil for API keys.
+func membershipFrom(c *gin.Context) *models.Membership {
+	value, exists := c.Get("membership")
+	if !exists {
+		return nil
+	}
+	membership, _ := value.(*models.Membership)
+	return membership
 }
@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"assessment/pkg/auth"
+	"assessment/pkg/database/mongodb/models"
+	"assessment/pkg/database/mongodb/repository"
+	"assessment/pkg/utils"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Password reset links that may be requested per email address within passwordResetRateWindow.
+const (
+	passwordResetRateLimit  = 5
+	passwordResetRateWindow = time.Hour
+)
+
+// ForgotPassword emails a single-use password reset link. It answers 202 before looking the
+// account up, so that neither the response nor its timing reveals whether an account exists.
+func ForgotPassword(c *gin.Context) {
+	var request models.ForgotPasswordRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON payload"})
+		return
+	}
+
+	// No account can exist for an address that does not normalize.
+	email, err := utils.NormalizeEmail(request.Email)
+	if err != nil {
+		c.JSON(http.StatusAccepted, gin.H{"message": "If an account exists for this email, a reset link has been sent"})
+		return
+	}
+
+	// Limit the emails that can be sent to an address, whether or not it has an account.
+	allowed, retryAfter, err := utils.AllowRequest("password_reset:"+email, passwordResetRateLimit, passwordResetRateWindow)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send reset link"})
+		return
+	}
+	if !allowed {
+		c.Header("Retry-After", strconv.Itoa(int(retryAfter.Seconds())))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many reset links requested, please try again later"})
+		return
+	}
+
+	go func() {
+		user, err := repository.NewUserRepo().FindUserByEmail(email)
+		if err != nil {
+			log.Printf("Failed to look up user for password reset: %v", err)
+		}
+		if user != nil {
+			if err := sendPasswordResetEmail(user); err != nil {
+				log.Printf("Failed to send password reset email to %s: %v", user.Email, err)
+			}
+		}
+	}()
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "If an account exists for this email, a reset link has been sent"})
+}
+
+// ResetPassword sets a new password using a reset token and signs the user out everywhere.
+func ResetPassword(c *gin.Context) {
+	var request models.ResetPasswordRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON payload"})
+		return
+	}
+
+	repo := repository.NewPasswordResetRepo()
+	reset, err := repo.GetPasswordReset(utils.HashToken(request.Token))
+	if err != nil || reset == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset token"})
+		return
+	}
+
+	user, err := repository.NewUserRepo().FindUserById(reset.UserId.Hex())
+	if err != nil || user == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset token"})
+		return
+	}
+
+	// Validate the new password before the token is spent.
+	if err := utils.ValidatePassword(request.Password, user.Name, user.Email); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+
+	hash, err := utils.HashPassword(request.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+
+	used, err := repo.MarkPasswordResetUsed(reset.Id)
+	if err != nil || !used {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset token"})
+		return
+	}
+
+	userID := reset.UserId.Hex()
+	err = repository.NewUserRepo().UpdatePassword(userID, hash)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	// Whoever knew the old password must not stay signed in, nor keep the tokens they created or
+	// the identity provider accounts they linked.
+	err = revokeAllSessions(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+	err = repository.NewPersonalTokenRepo().DeleteTokensByUser(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke access tokens"})
+		return
+	}
+	err = repository.NewExternalIdentityRepo().DeleteIdentitiesByUser(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlink identity providers"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully"})
+}
+
+// sendPasswordResetEmail stores a hashed reset token for the user and emails them the link.
+func sendPasswordResetEmail(user *models.User) error {
+	token, err := utils.GenerateSecureToken()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	err = repository.NewPasswordResetRepo().CreatePasswordReset(&models.PasswordReset{
+		UserId:    user.Id,
+		TokenHash: utils.HashToken(token),
+		CreatedAt: now,
+		ExpiresAt: now.Add(utils.PasswordResetExpiry),
+	})
+	if err != nil {
+		return err
+	}
+
+	link := emailLink("/reset-password", token)
+	body := fmt.Sprintf("A password reset was requested for your account. Open the link below to choose a new password.\n\n%s\n\nThe link expires in %s. If you did not request this, you can ignore this email.\n", link, utils.PasswordResetExpiry)
+
+	return utils.Mail().Send(user.Email, "Reset your password", body)
+}
+
+// ChangePassword replaces the password of the signed-in user after checking the current one,
+// signs them out of every other session and revokes their personal access tokens.
+func ChangePassword(c *gin.Context) {
+	var request models.ChangePasswordRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON payload"})
+		return
+	}
+
+	principal := auth.PrincipalFrom(c)
+	repo := repository.NewUserRepo()
+	user, err := repo.FindUserById(principal.UserId.Hex())
+	if err != nil || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	// Verify the current password against the stored hash.
+	isMatch, err := utils.CheckPasswordHash(request.CurrentPassword, user.Password)
+	if err != nil || !isMatch {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Current password is incorrect"})
+		return
+	}
+
+	if err := utils.ValidatePassword(request.NewPassword, user.Name, user.Email); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+
+	hash, err := utils.HashPassword(request.NewPassword)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+
+	err = repo.UpdatePassword(user.Id.Hex(), hash)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+		return
+	}
+
+	// Whoever learned the old password must not stay signed in nor keep the tokens they created.
+	// The session that proved the current password stays signed in.
+	err = revokeOtherSessions(user.Id.Hex(), principal.SessionId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+	err = repository.NewPersonalTokenRepo().DeleteTokensByUser(user.Id.Hex())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke access tokens"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed successfully"})
+}
+
+// respondValidationError rejects invalid input with 400. A password policy failure lists every violated rule.
+func respondValidationError(c *gin.Context, err error) {
+	var policyErr *utils.PasswordPolicyError
+	if errors.As(err, &policyErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password does not meet the requirements", "violations": policyErr.Violations})
+		return
+	}
+
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
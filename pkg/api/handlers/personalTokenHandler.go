@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"assessment/pkg/auth"
+	"assessment/pkg/database/mongodb/models"
+	"assessment/pkg/database/mongodb/repository"
+	"assessment/pkg/utils"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreatePersonalToken creates a personal access token for the signed-in user.
+// The token itself is only returned in this response.
+func CreatePersonalToken(c *gin.Context) {
+	var request models.PersonalAccessTokenRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON payload"})
+		return
+	}
+
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token name is required"})
+		return
+	}
+	if err := validateScopes(request.Scopes); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if request.ExpiresAt != nil && !request.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Expiry must be in the future"})
+		return
+	}
+
+	token, prefix, err := utils.GeneratePersonalAccessToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	principal := auth.PrincipalFrom(c)
+	personalToken := models.PersonalAccessToken{
+		UserId:      principal.UserId,
+		Name:        name,
+		Prefix:      prefix,
+		TokenHash:   utils.HashToken(token),
+		Scopes:      request.Scopes,
+		AuthMethods: principal.AuthMethods,
+		CreatedAt:   time.Now(),
+		ExpiresAt:   request.ExpiresAt,
+	}
+	_, err = repository.NewPersonalTokenRepo().CreateToken(&personalToken)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create token"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.PersonalAccessTokenResponse{
+		PersonalAccessToken: personalToken,
+		Token:               token,
+	})
+}
+
+// GetPersonalTokens lists the personal access tokens of the signed-in user.
+func GetPersonalTokens(c *gin.Context) {
+	principal := auth.PrincipalFrom(c)
+
+	tokens, err := repository.NewPersonalTokenRepo().GetTokensByUser(principal.UserId.Hex())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tokens"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// GetPersonalToken retrieves one personal access token of the signed-in user.
+func GetPersonalToken(c *gin.Context) {
+	tokenID := c.Param("token_id")
+
+	principal := auth.PrincipalFrom(c)
+
+	token, err := repository.NewPersonalTokenRepo().GetToken(principal.UserId.Hex(), tokenID)
+	if err != nil || token == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Token not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, token)
+}
+
+// UpdatePersonalToken renames a personal access token or replaces its scopes.
+func UpdatePersonalToken(c *gin.Context) {
+	tokenID := c.Param("token_id")
+	var request models.PersonalAccessTokenUpdateRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON payload"})
+		return
+	}
+
+	request.Name = strings.TrimSpace(request.Name)
+	if request.Scopes != nil {
+		if err := validateScopes(request.Scopes); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if request.Name == "" && request.Scopes == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Nothing to update"})
+		return
+	}
+
+	principal := auth.PrincipalFrom(c)
+
+	token, err := repository.NewPersonalTokenRepo().UpdateToken(principal.UserId.Hex(), tokenID, &request)
+	if err != nil || token == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Token not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, token)
+}
+
+// DeletePersonalToken revokes a personal access token of the signed-in user.
+func DeletePersonalToken(c *gin.Context) {
+	tokenID := c.Param("token_id")
+
+	principal := auth.PrincipalFrom(c)
+
+	deleted, err := repository.NewPersonalTokenRepo().DeleteToken(principal.UserId.Hex(), tokenID)
+	if err != nil || !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Token not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Token revoked"})
+}
+
+func validateScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return errors.New("At least one scope is required")
+	}
+	for _, scope := range scopes {
+		if !auth.IsValidScope(scope) {
+			return fmt.Errorf("Unknown scope %s", scope)
+		}
+	}
+	return nil
+}
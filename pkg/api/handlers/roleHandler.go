@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"assessment/pkg/auth"
+	"assessment/pkg/database/mongodb/models"
+	"assessment/pkg/database/mongodb/repository"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetOrganizationRoles lists the built-in and custom roles of an organization.
+func GetOrganizationRoles(c *gin.Context) {
+	c.JSON(http.StatusOK, auth.Roles(organizationFrom(c)))
+}
+
+// CreateOrganizationRole defines a new custom role for an organization.
+func CreateOrganizationRole(c *gin.Context) {
+	organizationID := c.Param("organization_id")
+	var requestBody models.RoleRequestBody
+
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON payload"})
+		return
+	}
+
+	role := models.Role{
+		Name:        strings.TrimSpace(requestBody.Name),
+		Permissions: requestBody.Permissions,
+	}
+	if role.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Role name is required"})
+		return
+	}
+	if auth.IsBuiltInRole(role.Name) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Role name is reserved for a built-in role"})
+		return
+	}
+	if err := validatePermissions(role.Permissions); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := checkRoleGrants(c, role.Permissions); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	added, err := repository.NewOrganizationRepo().AddRole(organizationID, &role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create role"})
+		return
+	}
+	if !added {
+		c.JSON(http.StatusConflict, gin.H{"error": "Role already exists"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, role)
+}
+
+// UpdateOrganizationRole replaces the permissions of a custom role.
+func UpdateOrganizationRole(c *gin.Context) {
+	organizationID := c.Param("organization_id")
+	roleName := c.Param("role_name")
+	var requestBody models.RoleUpdateRequestBody
+
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON payload"})
+		return
+	}
+
+	if auth.IsBuiltInRole(roleName) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Built-in roles cannot be modified"})
+		return
+	}
+	if err := validatePermissions(requestBody.Permissions); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := checkRoleGrants(c, requestBody.Permissions); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Editing a role changes the rights of its members, which must not be demoted by someone holding less.
+	role := auth.FindRole(organizationFrom(c), roleName)
+	if role == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+		return
+	}
+	if checkRoleGrants(c, role.Permissions) != nil {
+		members, err := repository.NewMembershipRepo().CountMembershipsByRole(organizationID, roleName)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+			return
+		}
+		if members > 0 {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Role is assigned to members with rights you do not hold"})
+			return
+		}
+	}
+
+	updated, err := repository.NewOrganizationRepo().UpdateRole(organizationID, roleName, requestBody.Permissions)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+		return
+	}
+	if !updated {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Role{Name: roleName, Permissions: requestBody.Permissions})
+}
+
+// DeleteOrganizationRole removes a custom role that is no longer assigned to anyone.
+func DeleteOrganizationRole(c *gin.Context) {
+	organizationID := c.Param("organization_id")
+	roleName := c.Param("role_name")
+
+	if auth.IsBuiltInRole(roleName) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Built-in roles cannot be deleted"})
+		return
+	}
+
+	// Refuse to strand members or pending invitations on a role that no longer exists.
+	members, err := repository.NewMembershipRepo().CountMembershipsByRole(organizationID, roleName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete role"})
+		return
+	}
+	invitations, err := repository.NewInvitationRepo().CountPendingInvitationsByRole(organizationID, roleName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete role"})
+		return
+	}
+	if members > 0 || invitations > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Role is still assigned to members or pending invitations"})
+		return
+	}
+	if saml := organizationFrom(c).SAML; saml != nil && saml.DefaultRole == roleName {
+		c.JSON(http.StatusConflict, gin.H{"error": "Role is given to users joining through single sign-on"})
+		return
+	}
+
+	deleted, err := repository.NewOrganizationRepo().DeleteRole(organizationID, roleName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete role"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role deleted successfully"})
+}
+
+// checkRoleGrants checks that the caller holds the permissions, so nobody can hand out more power
+// than they have themselves, whether to a role, an invitation or an integration. Users hold the
+// permissions of their role that their credential's scopes allow; service principals hold their scopes.
+func checkRoleGrants(c *gin.Context, permissions []string) error {
+	principal := auth.PrincipalFrom(c)
+	membership := membershipFrom(c)
+	for _, permission := range permissions {
+		held := false
+		if principal != nil && principal.IsService() {
+			held = principal.HasScope(permission)
+		} else if principal != nil && membership != nil {
+			held = auth.HasPermission(organizationFrom(c), membership.Role, permission) && principal.HasScope(permission)
+		}
+		if !held {
+			return fmt.Errorf("You cannot grant permission %s, which you do not hold", permission)
+		}
+	}
+	return nil
+}
+
+// validatePermissions checks that at least one permission is requested and that every one is known.
+func validatePermissions(permissions []string) error {
+	if len(permissions) == 0 {
+		return errors.New("At least one permission is required")
+	}
+	for _, permission := range permissions {
+		if !auth.IsValidPermission(permission) {
+			return fmt.Errorf("Unknown permission %s", permission)
+		}
+	}
+	return nil
+}
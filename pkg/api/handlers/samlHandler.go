@@ -0,0 +1,476 @@
+package handlers
+
+import (
+	"assessment/pkg/auth"
+	"assessment/pkg/database/mongodb/models"
+	"assessment/pkg/database/mongodb/repository"
+	"assessment/pkg/utils"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/crewjam/saml"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Reasons an account at the identity provider of an organization cannot be linked to a signed-in user.
+var (
+	errSAMLEmailMismatch  = errors.New("The identity provider reported another email address than the one of your account")
+	errSAMLIdentityLinked = errors.New("This identity provider account is already linked to another user")
+)
+
+// GetSAMLSettings returns the SAML identity provider configured for an organization.
+func GetSAMLSettings(c *gin.Context) {
+	organization := organizationFrom(c)
+	if organization.SAML == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "SAML single sign-on is not configured"})
+		return
+	}
+
+	c.JSON(http.StatusOK, organization.SAML)
+}
+
+// ConfigureSAML sets the SAML identity provider of an organization from its metadata XML.
+// Members signed in through a previous identity provider must sign in again to be linked.
+func ConfigureSAML(c *gin.Context) {
+	organizationID := c.Param("organization_id")
+	var request models.SAMLSettingsRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON payload"})
+		return
+	}
+
+	metadata, err := utils.ParseSAMLMetadata([]byte(request.IDPMetadata))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid identity provider metadata: " + err.Error()})
+		return
+	}
+
+	// Ownership cannot be handed out by an identity provider.
+	organization := organizationFrom(c)
+	if request.DefaultRole == "" {
+		request.DefaultRole = models.RoleMember
+	}
+	if request.DefaultRole == models.RoleOwner || auth.FindRole(organization, request.DefaultRole) == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role"})
+		return
+	}
+
+	settings := models.SAMLSettings{
+		IDPEntityID:    metadata.EntityID,
+		IDPMetadata:    request.IDPMetadata,
+		EmailAttribute: strings.TrimSpace(request.EmailAttribute),
+		NameAttribute:  strings.TrimSpace(request.NameAttribute),
+		DefaultRole:    request.DefaultRole,
+		UpdatedAt:      time.Now(),
+	}
+	err = repository.NewOrganizationRepo().SetSAMLSettings(organizationID, &settings)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to configure single sign-on"})
+		return
+	}
+
+	// Subjects of another identity provider may name different users.
+	if organization.SAML != nil && organization.SAML.IDPEntityID != settings.IDPEntityID {
+		if err := repository.NewExternalIdentityRepo().DeleteIdentitiesByProvider(samlProvider(organizationID)); err != nil {
+			log.Printf("Failed to unlink single sign-on identities of organization %s: %v", organizationID, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// DeleteSAMLSettings removes the SAML identity provider of an organization, which also stops enforcing single sign-on.
+func DeleteSAMLSettings(c *gin.Context) {
+	organizationID := c.Param("organization_id")
+
+	if organizationFrom(c).SAML == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "SAML single sign-on is not configured"})
+		return
+	}
+
+	err := repository.NewOrganizationRepo().RemoveSAMLSettings(organizationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove single sign-on"})
+		return
+	}
+
+	if err := repository.NewExternalIdentityRepo().DeleteIdentitiesByProvider(samlProvider(organizationID)); err != nil {
+		log.Printf("Failed to unlink single sign-on identities of organization %s: %v", organizationID, err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Single sign-on removed"})
+}
+
+// GetSAMLMetadata publishes the SAML service provider metadata of an organization, which its
+// identity provider is set up with.
+func GetSAMLMetadata(c *gin.Context) {
+	organizationID := c.Param("org_id")
+
+	organization, err := repository.NewOrganizationRepo().GetOrganizationById(organizationID)
+	if err != nil || organization == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	metadata, err := utils.SAMLMetadata(utils.NewSAMLServiceProvider(organizationID, nil))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate metadata"})
+		return
+	}
+
+	c.Data(http.StatusOK, "application/samlmetadata+xml", metadata)
+}
+
+// BeginSAMLLogin sends the user to the SAML identity provider of an organization to sign in.
+func BeginSAMLLogin(c *gin.Context) {
+	organizationID := c.Param("org_id")
+
+	sp, _, err := samlServiceProvider(organizationID)
+	if err != nil || sp == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "SAML single sign-on is not configured"})
+		return
+	}
+
+	redirectURL, err := samlLoginURL(sp, organizationID, "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start sign-in"})
+		return
+	}
+
+	c.Redirect(http.StatusFound, redirectURL)
+}
+
+// LinkSAMLAccount starts linking the account of the signed-in member to the SAML identity provider
+// of the organization. The member signs in at the returned URL with an identity provider account
+// that has the same email address.
+func LinkSAMLAccount(c *gin.Context) {
+	organizationID := c.Param("organization_id")
+
+	sp, _, err := samlServiceProvider(organizationID)
+	if err != nil || sp == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "SAML single sign-on is not configured"})
+		return
+	}
+
+	redirectURL, err := samlLoginURL(sp, organizationID, auth.PrincipalFrom(c).UserId.Hex())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start linking"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SAMLLinkResponse{RedirectURL: redirectURL})
+}
+
+// SAMLAssertionConsumer completes a sign-in with the SAML identity provider of an organization.
+// The response must answer a request sent by BeginSAMLLogin or LinkSAMLAccount and be signed by
+// the identity provider. The user is found or created from the assertion and joins the organization.
+func SAMLAssertionConsumer(c *gin.Context) {
+	organizationID := c.Param("org_id")
+
+	sp, organization, err := samlServiceProvider(organizationID)
+	if err != nil || sp == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "SAML single sign-on is not configured"})
+		return
+	}
+
+	if err := c.Request.ParseForm(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	request, err := utils.ConsumeSAMLRequest(c.Request.PostForm.Get("RelayState"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+		return
+	}
+	if request == nil || request.OrganizationId != organizationID {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired sign-in"})
+		return
+	}
+
+	assertion, err := sp.ParseResponse(c.Request, []string{request.RequestId})
+	if err != nil {
+		var invalid *saml.InvalidResponseError
+		if errors.As(err, &invalid) {
+			err = invalid.PrivateErr
+		}
+		log.Printf("Rejected SAML response for organization %s: %v", organizationID, err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid SAML response"})
+		return
+	}
+
+	account, err := samlAccount(organization, assertion)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The identity provider is run by the organization, which speaks for an address only once it
+	// proved owning its domain. Any other existing account is linked by its user while signed in.
+	var user *models.User
+	if request.UserId != "" {
+		user, err = linkSAMLAccount(request.UserId, account)
+	} else {
+		user, err = resolveExternalUser(account, func(user *models.User) (bool, error) {
+			return account.EmailVerified && user.EmailVerifiedAt != nil, nil
+		}, account.EmailVerified)
+	}
+	if err == errExternalEmailMissing || err == errSAMLEmailMismatch {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err == errExternalSignupRefused {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Accounts are only created for email addresses in a domain verified by the organization"})
+		return
+	}
+	if err == errExternalAccountExists {
+		c.JSON(http.StatusConflict, gin.H{"error": "An account with this email address already exists, sign in and link it to single sign-on"})
+		return
+	}
+	if err == errSAMLIdentityLinked {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to resolve SAML user for organization %s: %v", organizationID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+		return
+	}
+
+	// Another organization enforcing single sign-on is only satisfied by its own identity provider.
+	if refuseUnlessSSOAllowed(c, user, organizationID) {
+		return
+	}
+
+	if err := provisionSAMLMembership(organization, user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to join organization"})
+		return
+	}
+
+	// Users with two-factor authentication must present their second factor before receiving tokens.
+	if requireSecondFactor(c, user, models.AuthMethodFederated) {
+		return
+	}
+
+	access_token, refresh_token, err := startSession(c, user, models.AuthMethodFederated)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.AuthResponse{
+		Message:      "SignIn successful",
+		AccessToken:  access_token,
+		RefreshToken: refresh_token,
+	})
+}
+
+// samlServiceProvider returns the SAML service provider of an organization with the organization.
+// It returns nil without an error when the organization has no identity provider.
+func samlServiceProvider(organizationID string) (*saml.ServiceProvider, *models.Organization, error) {
+	organization, err := repository.NewOrganizationRepo().GetOrganizationById(organizationID)
+	if err != nil || organization == nil || organization.SAML == nil {
+		return nil, nil, err
+	}
+
+	metadata, err := utils.ParseSAMLMetadata([]byte(organization.SAML.IDPMetadata))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return utils.NewSAMLServiceProvider(organizationID, metadata), organization, nil
+}
+
+// samlAccount maps a verified assertion to the user's account at the organization's identity
+// provider. The email address falls back to the subject when it has the email address format.
+func samlAccount(organization *models.Organization, assertion *saml.Assertion) (externalAccount, error) {
+	if assertion.Subject == nil || assertion.Subject.NameID == nil || assertion.Subject.NameID.Value == "" {
+		return externalAccount{}, errors.New("The SAML assertion has no subject")
+	}
+	nameID := assertion.Subject.NameID
+
+	emailAttributes := utils.SAMLEmailAttributes
+	if organization.SAML.EmailAttribute != "" {
+		emailAttributes = []string{organization.SAML.EmailAttribute}
+	}
+	nameAttributes := utils.SAMLNameAttributes
+	if organization.SAML.NameAttribute != "" {
+		nameAttributes = []string{organization.SAML.NameAttribute}
+	}
+
+	email := utils.SAMLAttribute(assertion, emailAttributes...)
+	if email == "" && nameID.Format == string(saml.EmailAddressNameIDFormat) {
+		email = nameID.Value
+	}
+	if email != "" {
+		normalized, err := utils.NormalizeEmail(email)
+		if err != nil {
+			return externalAccount{}, errors.New("The SAML assertion has an invalid email address")
+		}
+		email = normalized
+	}
+
+	// Only addresses in a domain the organization verified count as verified.
+	return externalAccount{
+		Provider:      samlProvider(organization.Id.Hex()),
+		Subject:       nameID.Value,
+		Email:         email,
+		EmailVerified: email != "" && ownsEmailDomain(organization, email),
+		Name:          utils.SAMLAttribute(assertion, nameAttributes...),
+	}, nil
+}
+
+// linkSAMLAccount links an account at the identity provider of an organization to the user who
+// started linking it with LinkSAMLAccount. The identity provider must report the user's email
+// address, so that a link started by someone else cannot take over the sign-in of the user.
+func linkSAMLAccount(userID string, account externalAccount) (*models.User, error) {
+	user, err := repository.NewUserRepo().FindUserById(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("linking user not found")
+	}
+
+	if account.Email == "" {
+		return nil, errExternalEmailMissing
+	}
+	email, err := utils.NormalizeEmail(user.Email)
+	if err != nil || email != account.Email {
+		return nil, errSAMLEmailMismatch
+	}
+
+	identityRepo := repository.NewExternalIdentityRepo()
+	link, err := identityRepo.GetIdentity(account.Provider, account.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if link != nil {
+		if link.UserId != user.Id {
+			return nil, errSAMLIdentityLinked
+		}
+		return user, nil
+	}
+
+	now := time.Now()
+	err = identityRepo.CreateIdentity(&models.ExternalIdentity{
+		UserId:      user.Id,
+		Provider:    account.Provider,
+		Subject:     account.Subject,
+		Email:       account.Email,
+		CreatedAt:   now,
+		LastLoginAt: now,
+	})
+	if err == repository.ErrIdentityExists {
+		return nil, errSAMLIdentityLinked
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// samlLoginURL creates an authentication request to the identity provider of an organization and
+// returns the URL sending the user there. userID is set when a signed-in user links their account.
+func samlLoginURL(sp *saml.ServiceProvider, organizationID, userID string) (string, error) {
+	request, err := sp.MakeAuthenticationRequest(sp.GetSSOBindingLocation(saml.HTTPRedirectBinding), saml.HTTPRedirectBinding, saml.HTTPPostBinding)
+	if err != nil {
+		return "", err
+	}
+
+	// The relay state comes back with the response and ties it to this request.
+	relayState, err := utils.GenerateSecureToken()
+	if err != nil {
+		return "", err
+	}
+	err = utils.StoreSAMLRequest(relayState, &utils.SAMLRequest{
+		OrganizationId: organizationID,
+		RequestId:      request.ID,
+		UserId:         userID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	redirectURL, err := request.Redirect(relayState, sp)
+	if err != nil {
+		return "", err
+	}
+	return redirectURL.String(), nil
+}
+
+// provisionSAMLMembership adds a user signing in with single sign-on to the organization with its
+// default role, unless the user already is a member.
+func provisionSAMLMembership(organization *models.Organization, user *models.User) error {
+	repo := repository.NewMembershipRepo()
+
+	membership, err := repo.GetMembership(organization.Id.Hex(), user.Id.Hex())
+	if err != nil || membership != nil {
+		return err
+	}
+
+	role := organization.SAML.DefaultRole
+	if auth.FindRole(organization, role) == nil {
+		role = models.RoleMember
+	}
+
+	_, err = repo.CreateMembership(&models.Membership{
+		UserId:         user.Id,
+		OrganizationId: organization.Id,
+		Role:           role,
+		JoinedAt:       time.Now(),
+	})
+	return err
+}
+
+// refuseUnlessSSOAllowed answers a sign-in with 403 when the user belongs to an organization that
+// requires single sign-on, and reports whether it did. A SAML sign-in passes the organization whose
+// identity provider vouched for the user, which satisfies that organization only.
+func refuseUnlessSSOAllowed(c *gin.Context, user *models.User, viaOrganizationID string) bool {
+	enforced, err := ssoEnforced(user.Id.Hex(), viaOrganizationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+		return true
+	}
+	if enforced {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Your organization requires signing in with single sign-on"})
+		return true
+	}
+	return false
+}
+
+// ssoEnforced reports whether the user belongs to an organization other than viaOrganizationID
+// that requires its members to sign in with single sign-on.
+func ssoEnforced(userID, viaOrganizationID string) (bool, error) {
+	memberships, err := repository.NewMembershipRepo().GetMembershipsByUser(userID)
+	if err != nil || len(memberships) == 0 {
+		return false, err
+	}
+
+	organizationIDs := make([]primitive.ObjectID, 0, len(memberships))
+	for _, membership := range memberships {
+		organizationIDs = append(organizationIDs, membership.OrganizationId)
+	}
+	organizations, err := repository.NewOrganizationRepo().GetOrganizationsByIds(organizationIDs)
+	if err != nil {
+		return false, err
+	}
+
+	for _, organization := range organizations {
+		if organization.EnforceSSO && organization.Id.Hex() != viaOrganizationID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// samlProvider names the identity provider of an organization in linked external identities.
+func samlProvider(organizationID string) string {
+	return "saml:" + organizationID
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"assessment/pkg/auth"
+	"assessment/pkg/database/mongodb/models"
+	"assessment/pkg/database/mongodb/repository"
+	"assessment/pkg/utils"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetSessions lists the active sessions of the signed-in user.
+func GetSessions(c *gin.Context) {
+	principal := auth.PrincipalFrom(c)
+
+	sessions, err := repository.NewSessionRepo().GetActiveSessionsByUser(principal.UserId.Hex())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sessions"})
+		return
+	}
+
+	// Flag the session the request was made with.
+	for _, session := range sessions {
+		session.Current = session.Id.Hex() == principal.SessionId
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+// RevokeSession signs the user out of one of their sessions.
+func RevokeSession(c *gin.Context) {
+	sessionID := c.Param("id")
+
+	principal := auth.PrincipalFrom(c)
+
+	repo := repository.NewSessionRepo()
+	session, err := repo.GetSessionById(sessionID)
+	if err != nil || session == nil || session.UserId != principal.UserId || session.RevokedAt != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+
+	err = revokeSession(principal.UserId.Hex(), session)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked"})
+}
+
+// LogoutAll signs the user out of every session, including the current one.
+func LogoutAll(c *gin.Context) {
+	principal := auth.PrincipalFrom(c)
+
+	err := revokeAllSessions(principal.UserId.Hex())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Signed out of all sessions"})
+}
+
+// startSession records a new session for the user signing in from this request
+// and issues the access and refresh tokens bound to it. authMethods lists how the user
+// proved their identity and defaults to a password.
+func startSession(c *gin.Context, user *models.User, authMethods ...string) (accessToken string, refreshToken string, err error) {
+	familyID, err := utils.StartRefreshFamily(user.Id.Hex())
+	if err != nil {
+		return "", "", err
+	}
+
+	if len(authMethods) == 0 {
+		authMethods = []string{models.AuthMethodPassword}
+	}
+
+	now := time.Now()
+	sessionID, err := repository.NewSessionRepo().CreateSession(&models.Session{
+		UserId:        user.Id,
+		UserAgent:     c.Request.UserAgent(),
+		IP:            c.ClientIP(),
+		RefreshFamily: familyID,
+		AuthMethods:   authMethods,
+		CreatedAt:     now,
+		LastUsedAt:    now,
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	return utils.GenerateTokens(user.Id.Hex(), user.Email, sessionID, familyID)
+}
+
+// startClientSession records a session for an OAuth client the user authorized and issues
+// the client's tokens bound to it, limited to the granted scopes. The session shows up in
+// the user's session list, where revoking it withdraws the client's access.
+func startClientSession(c *gin.Context, user *models.User, client *models.OAuthClient, scopes, authMethods []string) (accessToken string, refreshToken string, err error) {
+	familyID, err := utils.StartRefreshFamily(user.Id.Hex())
+	if err != nil {
+		return "", "", err
+	}
+
+	now := time.Now()
+	sessionID, err := repository.NewSessionRepo().CreateSession(&models.Session{
+		UserId:        user.Id,
+		UserAgent:     c.Request.UserAgent(),
+		IP:            c.ClientIP(),
+		RefreshFamily: familyID,
+		AuthMethods:   authMethods,
+		ClientId:      client.Id.Hex(),
+		Scopes:        scopes,
+		CreatedAt:     now,
+		LastUsedAt:    now,
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	return utils.GenerateOAuthTokens(user.Id.Hex(), user.Email, sessionID, familyID, client.Id.Hex(), scopes)
+}
+
+// revokeAllSessions signs a user out everywhere by revoking all their sessions and refresh tokens.
+func revokeAllSessions(userID string) error {
+	err := repository.NewSessionRepo().RevokeUserSessions(userID, "")
+	if err != nil {
+		return err
+	}
+
+	return utils.RevokeUserRefreshTokens(userID, "")
+}
+
+// revokeOtherSessions signs a user out of every session except keepSessionID.
+func revokeOtherSessions(userID, keepSessionID string) error {
+	repo := repository.NewSessionRepo()
+
+	keep, err := repo.GetSessionById(keepSessionID)
+	if err != nil {
+		return err
+	}
+	if keep == nil {
+		return revokeAllSessions(userID)
+	}
+
+	err = repo.RevokeUserSessions(userID, keepSessionID)
+	if err != nil {
+		return err
+	}
+
+	return utils.RevokeUserRefreshTokens(userID, keep.RefreshFamily)
+}
+
+// revokeSession marks a session as revoked and revokes its refresh token family.
+func revokeSession(userID string, session *models.Session) error {
+	err := repository.NewSessionRepo().RevokeSession(session.Id.Hex())
+	if err != nil {
+		return err
+	}
+
+	return utils.RevokeRefreshFamily(userID, session.RefreshFamily)
+}
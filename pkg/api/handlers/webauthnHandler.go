@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"assessment/pkg/auth"
+	"assessment/pkg/database/mongodb/models"
+	"assessment/pkg/database/mongodb/repository"
+	"assessment/pkg/utils"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BeginWebAuthnRegistration returns the options for registering a new passkey for the signed-in user.
+func BeginWebAuthnRegistration(c *gin.Context) {
+	principal := auth.PrincipalFrom(c)
+
+	credentials, err := repository.NewWebAuthnRepo().GetCredentialsByUser(principal.UserId.Hex())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch passkeys"})
+		return
+	}
+
+	challenge, err := utils.StartWebAuthnCeremony(utils.WebAuthnCeremonyRegistration, principal.UserId.Hex())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start registration"})
+		return
+	}
+
+	rp := utils.WebAuthnRP()
+	options := models.WebAuthnCreationOptions{
+		Challenge: challenge,
+		RP:        models.WebAuthnRelyingPartyEntity{Id: rp.ID, Name: rp.Name},
+		User: models.WebAuthnUserEntity{
+			Id:          base64.RawURLEncoding.EncodeToString(principal.UserId[:]),
+			Name:        principal.Email,
+			DisplayName: principal.Name,
+		},
+		Timeout:     utils.WebAuthnChallengeExpiry.Milliseconds(),
+		Attestation: "none",
+		// Registering the same authenticator twice is refused by the browser.
+		ExcludeCredentials: credentialDescriptors(credentials),
+		AuthenticatorSelection: models.WebAuthnAuthenticatorSelection{
+			ResidentKey:      "preferred",
+			UserVerification: "preferred",
+		},
+	}
+	for _, algorithm := range utils.WebAuthnAlgorithms {
+		options.PubKeyCredParams = append(options.PubKeyCredParams, models.WebAuthnCredentialParameter{Type: "public-key", Alg: algorithm})
+	}
+
+	c.JSON(http.StatusOK, options)
+}
+
+// FinishWebAuthnRegistration verifies the authenticator's response to the registration
+// challenge and stores the new passkey.
+func FinishWebAuthnRegistration(c *gin.Context) {
+	var request models.WebAuthnRegistrationRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON payload"})
+		return
+	}
+
+	clientDataJSON, err1 := utils.DecodeBase64URL(request.Response.ClientDataJSON)
+	attestationObject, err2 := utils.DecodeBase64URL(request.Response.AttestationObject)
+	if err1 != nil || err2 != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid credential encoding"})
+		return
+	}
+
+	// The challenge must have been issued to this user for a registration.
+	principal := auth.PrincipalFrom(c)
+	ceremony, err := utils.ConsumeWebAuthnCeremony(clientDataJSON, utils.WebAuthnCeremonyRegistration)
+	if err != nil || ceremony.UserId != principal.UserId.Hex() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Registration challenge is invalid or has expired"})
+		return
+	}
+
+	authData, err := utils.WebAuthnRP().VerifyRegistration(ceremony.Challenge, clientDataJSON, attestationObject)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Passkey registration failed: " + err.Error()})
+		return
+	}
+
+	// A credential ID belongs to exactly one account.
+	repo := repository.NewWebAuthnRepo()
+	credentialID := base64.RawURLEncoding.EncodeToString(authData.CredentialId)
+	existing, err := repo.GetCredential(credentialID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register passkey"})
+		return
+	}
+	if existing != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Passkey is already registered"})
+		return
+	}
+
+	name := request.Name
+	if name == "" {
+		name = "Passkey"
+	}
+
+	credential := &models.WebAuthnCredential{
+		UserId:       principal.UserId,
+		CredentialId: credentialID,
+		PublicKey:    authData.PublicKey,
+		SignCount:    authData.SignCount,
+		Name:         name,
+		CreatedAt:    time.Now(),
+	}
+	_, err = repo.CreateCredential(credential)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register passkey"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Passkey registered successfully", "credential_id": credentialID})
+}
+
+// BeginWebAuthnLogin returns the options for signing in with a passkey. Without an email the
+// browser offers the discoverable passkeys it holds for this site.
+func BeginWebAuthnLogin(c *gin.Context) {
+	var request models.WebAuthnLoginBeginRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON payload"})
+		return
+	}
+
+	// Unknown emails get the same response as known ones, so accounts cannot be discovered.
+	var userID string
+	var credentials []*models.WebAuthnCredential
+	if request.Email != "" {
+		user, err := repository.NewUserRepo().FindUserByEmail(request.Email)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start sign-in"})
+			return
+		}
+		if user != nil {
+			userID = user.Id.Hex()
+			credentials, err = repository.NewWebAuthnRepo().GetCredentialsByUser(userID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start sign-in"})
+				return
+			}
+		}
+	}
+
+	challenge, err := utils.StartWebAuthnCeremony(utils.WebAuthnCeremonyLogin, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start sign-in"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.WebAuthnRequestOptions{
+		Challenge: challenge,
+		RPId:      utils.WebAuthnRP().ID,
+		Timeout:   utils.WebAuthnChallengeExpiry.Milliseconds(),
+		// Passwordless sign-in must prove both possession and the user, e.g. by PIN or biometrics.
+		UserVerification: "required",
+		AllowCredentials: credentialDescriptors(credentials),
+	})
+}
+
+// FinishWebAuthnLogin verifies the passkey assertion and signs the owning user in.
+func FinishWebAuthnLogin(c *gin.Context) {
+	var request models.WebAuthnLoginRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON payload"})
+		return
+	}
+
+	clientDataJSON, err1 := utils.DecodeBase64URL(request.Response.ClientDataJSON)
+	authenticatorData, err2 := utils.DecodeBase64URL(request.Response.AuthenticatorData)
+	signature, err3 := utils.DecodeBase64URL(request.Response.Signature)
+	if err1 != nil || err2 != nil || err3 != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid credential encoding"})
+		return
+	}
+
+	ceremony, err := utils.ConsumeWebAuthnCeremony(clientDataJSON, utils.WebAuthnCeremonyLogin)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Sign-in challenge is invalid or has expired"})
+		return
+	}
+
+	repo := repository.NewWebAuthnRepo()
+	credential, err := repo.GetCredential(request.Id)
+	if err != nil || credential == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		return
+	}
+
+	// A challenge issued for one account cannot be answered with another account's passkey.
+	if ceremony.UserId != "" && ceremony.UserId != credential.UserId.Hex() {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		return
+	}
+	if request.Response.UserHandle != "" && request.Response.UserHandle != base64.RawURLEncoding.EncodeToString(credential.UserId[:]) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		return
+	}
+
+	authData, err := utils.WebAuthnRP().VerifyAssertion(ceremony.Challenge, credential.PublicKey, clientDataJSON, authenticatorData, signature)
+	if err != nil || !authData.UserVerified() {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		return
+	}
+
+	// A counter that does not increase suggests the authenticator has been cloned.
+	// Authenticators without a counter always report zero.
+	if (authData.SignCount != 0 || credential.SignCount != 0) && authData.SignCount <= credential.SignCount {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Passkey sign counter did not increase, the authenticator may have been cloned"})
+		return
+	}
+	recorded, err := repo.RecordCredentialUse(credential.Id, credential.SignCount, authData.SignCount)
+	if err != nil || !recorded {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		return
+	}
+
+	user, err := repository.NewUserRepo().FindUserById(credential.UserId.Hex())
+	if err != nil || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		return
+	}
+
+	if refuseUnlessSSOAllowed(c, user, "") {
+		return
+	}
+
+	// Start a session and generate authentication tokens for the authenticated user.
+	access_token, refresh_token, err := startSession(c, user, models.AuthMethodHardwareKey, models.AuthMethodMultiFactor)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.AuthResponse{
+		Message:      "SignIn successful",
+		AccessToken:  access_token,
+		RefreshToken: refresh_token,
+	})
+}
+
+// credentialDescriptors lists passkeys in the form expected by the browser.
+func credentialDescriptors(credentials []*models.WebAuthnCredential) []models.WebAuthnCredentialDescriptor {
+	descriptors := []models.WebAuthnCredentialDescriptor{}
+	for _, credential := range credentials {
+		descriptors = append(descriptors, models.WebAuthnCredentialDescriptor{Type: "public-key", Id: credential.CredentialId})
+	}
+	return descriptors
+}
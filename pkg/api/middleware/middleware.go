@@ -1,8 +1,11 @@
 package middleware
 
 import (
+	"assessment/config"
+	"assessment/pkg/auth"
 	"assessment/pkg/database/mongodb/repository"
 	"assessment/pkg/utils"
+	"log"
 	"net/http"
 	"strings"
 
@@ -23,8 +26,22 @@ func AuthMiddleware() gin.HandlerFunc {
 		// Extract the token string after removing the "Bearer" prefix.
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 
+		// Personal access tokens are opaque and looked up by hash instead of being verified as JWTs.
+		if strings.HasPrefix(tokenString, utils.PersonalAccessTokenPrefix) {
+			if authenticatePersonalToken(c, tokenString) {
+				c.Next()
+			}
+			return
+		}
+		if strings.HasPrefix(tokenString, utils.APIKeyPrefix) {
+			if authenticateAPIKey(c, tokenString) {
+				c.Next()
+			}
+			return
+		}
+
 		// Validate the extracted token.
-		_, err := utils.ValidateToken(tokenString)
+		claims, err := utils.Tokens().Parse(tokenString, utils.TokenTypeAccess)
 		if err != nil {
 			// If the token is invalid, respond with an Unauthorized status.
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
@@ -32,58 +49,282 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// Client credentials tokens belong to an OAuth client acting for its organization, not to a session.
+		if claims.SessionId == "" && claims.ClientId != "" {
+			if authenticateOAuthClient(c, claims) {
+				c.Next()
+			}
+			return
+		}
+
+		// Reject tokens whose session has been signed out.
+		session, err := repository.NewSessionRepo().GetSessionById(claims.SessionId)
+		if err != nil || session == nil || session.RevokedAt != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has been revoked"})
+			c.Abort()
+			return
+		}
+
+		// Load the user the token was issued to.
+		user, err := repository.NewUserRepo().FindUserById(claims.Subject)
+		if err != nil || user == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+			c.Abort()
+			return
+		}
+
+		principal := &auth.Principal{
+			UserId:        user.Id,
+			Email:         user.Email,
+			Name:          user.Name,
+			SessionId:     claims.SessionId,
+			EmailVerified: user.EmailVerifiedAt != nil,
+			AuthMethods:   session.AuthMethods,
+		}
+
+		// An OAuth client acting for the user is limited to the scopes the user consented to.
+		if claims.ClientId != "" {
+			client, err := repository.NewOAuthClientRepo().GetClientById(claims.ClientId)
+			if err != nil || client == nil {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+				c.Abort()
+				return
+			}
+			principal.SessionId = ""
+			principal.ClientId = claims.ClientId
+			principal.Scopes = utils.ParseScope(claims.Scope)
+		}
+
+		// Make the caller available to downstream handlers.
+		auth.SetPrincipal(c, principal)
+
 		// Proceed to the next handler if the token is valid.
 		c.Next()
 	}
 }
 
-// InviteMiddleware verifies if the user is authorized to perform actions related to invitations.
-func InviteMiddleware() gin.HandlerFunc {
+// authenticatePersonalToken sets the principal for a personal access token, or responds
+// with 401 and aborts. It reports whether the request was authenticated.
+func authenticatePersonalToken(c *gin.Context, tokenString string) bool {
+	repo := repository.NewPersonalTokenRepo()
+	token, err := repo.GetTokenByHash(utils.HashToken(tokenString))
+	if err != nil || token == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		c.Abort()
+		return false
+	}
+
+	user, err := repository.NewUserRepo().FindUserById(token.UserId.Hex())
+	if err != nil || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		c.Abort()
+		return false
+	}
+
+	if err := repo.RecordTokenUse(token.Id, c.ClientIP()); err != nil {
+		log.Printf("Failed to record use of personal access token %s: %v", token.Id.Hex(), err)
+	}
+
+	// The token can do no more than its scopes allow; an empty list grants nothing.
+	scopes := token.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	auth.SetPrincipal(c, &auth.Principal{
+		UserId:        user.Id,
+		Email:         user.Email,
+		Name:          user.Name,
+		TokenId:       token.Id.Hex(),
+		EmailVerified: user.EmailVerifiedAt != nil,
+		AuthMethods:   token.AuthMethods,
+		Scopes:        scopes,
+	})
+	return true
+}
+
+// authenticateAPIKey sets a service principal for an organization API key, or responds with
+// 401 or 403 and aborts. It reports whether the request was authenticated.
+func authenticateAPIKey(c *gin.Context, keyString string) bool {
+	repo := repository.NewAPIKeyRepo()
+	key, err := repo.GetAPIKeyByHash(utils.HashToken(keyString))
+	if err != nil || key == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+		c.Abort()
+		return false
+	}
+
+	// Keys restricted to known addresses are useless to anyone who copies them elsewhere.
+	if !utils.IPAllowed(c.ClientIP(), key.AllowedIPs) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "API key is not allowed from this address"})
+		c.Abort()
+		return false
+	}
+
+	if err := repo.RecordAPIKeyUse(key.Id, c.ClientIP()); err != nil {
+		log.Printf("Failed to record use of API key %s: %v", key.Id.Hex(), err)
+	}
+
+	// The key can do no more than its permissions allow; an empty list grants nothing.
+	permissions := key.Permissions
+	if permissions == nil {
+		permissions = []string{}
+	}
+	auth.SetPrincipal(c, &auth.Principal{
+		Name:           key.Name,
+		APIKeyId:       key.Id.Hex(),
+		OrganizationId: key.OrganizationId,
+		Scopes:         permissions,
+	})
+	return true
+}
+
+// authenticateOAuthClient sets a service principal for a client credentials token, or responds
+// with 401 and aborts. It reports whether the request was authenticated.
+func authenticateOAuthClient(c *gin.Context, claims *utils.TokenClaims) bool {
+	// The client may have been deleted since the token was issued.
+	client, err := repository.NewOAuthClientRepo().GetClientById(claims.ClientId)
+	if err != nil || client == nil || client.OrganizationId.Hex() != claims.OrganizationId {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		c.Abort()
+		return false
+	}
+
+	auth.SetPrincipal(c, &auth.Principal{
+		Name:           client.Name,
+		ClientId:       claims.ClientId,
+		OrganizationId: client.OrganizationId,
+		Scopes:         utils.ParseScope(claims.Scope),
+	})
+	return true
+}
+
+// RequireSession refuses requests made with a personal access token, API key or OAuth token. It guards the endpoints
+// that manage the account itself, which must not be reachable with a token from a script.
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := auth.PrincipalFrom(c)
+		if principal == nil || principal.SessionId == "" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "This endpoint requires a signed-in session"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireScope refuses requests whose credential does not grant the scope.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := auth.PrincipalFrom(c)
+		if principal == nil || !principal.HasScope(scope) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Token is missing scope " + scope})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireAdmin only lets through operators listed in admin_emails of the app configuration
+// who have verified their email address.
+func RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := auth.PrincipalFrom(c)
+		if principal == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			c.Abort()
+			return
+		}
+
+		for _, email := range config.GetAppConfig().AdminEmails {
+			if principal.EmailVerified && strings.EqualFold(email, principal.Email) {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{"error": "Administrator access required"})
+		c.Abort()
+	}
+}
+
+// RequirePermission verifies that the caller holds a role granting the permission in the
+// organization from the URL and stores the organization and membership in the context for the handlers.
+// API keys and OAuth client credentials are authorized by their own permissions instead and get no membership.
+func RequirePermission(permission string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Retrieve the organization ID from the URL parameter.
 		organizationID := c.Param("organization_id")
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is missing"})
+
+		// Resolve the caller authenticated by AuthMiddleware.
+		principal := auth.PrincipalFrom(c)
+		if principal == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
 			c.Abort()
 			return
 		}
 
-		// Extract the token string after removing the "Bearer" prefix.
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-
-		userEmail, err := utils.GetEmailFromToken(tokenString)
+		organization, err := repository.NewOrganizationRepo().GetOrganizationById(organizationID)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
 			c.Abort()
 			return
 		}
 
-		// Create a new instance of the Organization repository.
-		repo := repository.NewOrganizationRepo()
-		organization, err := repo.GetOrganizationById(organizationID)
+		// Service principals have no membership; their credential alone says what they may do.
+		if principal.IsService() {
+			if principal.OrganizationId != organization.Id {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Credential does not belong to the organization"})
+				c.Abort()
+				return
+			}
+			if !principal.HasScope(permission) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Credential is missing permission " + permission})
+				c.Abort()
+				return
+			}
+
+			c.Set("organization", organization)
+			c.Next()
+			return
+		}
+
+		// Load the caller's role in the organization.
+		membership, err := repository.NewMembershipRepo().GetMembership(organizationID, principal.UserId.Hex())
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get organization"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get membership"})
 			c.Abort()
 			return
 		}
 
-		// Check if the user is in the list of invited users for the organization.
-		isInvited := false
-		for _, invitedUser := range organization.InvitedUsers {
-			if invitedUser == userEmail {
-				isInvited = true
-				break
-			}
+		// Authenticated callers without the permission are forbidden, not unauthorized.
+		if membership == nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "User is not a member of the organization"})
+			c.Abort()
+			return
+		}
+		if !auth.HasPermission(organization, membership.Role, permission) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Missing permission " + permission})
+			c.Abort()
+			return
+		}
+		if !principal.HasScope(permission) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Token is missing scope " + permission})
+			c.Abort()
+			return
 		}
 
-		// If the user is not invited, respond with an Unauthorized status.
-		if !isInvited {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "User is not invited to the organization"})
+		// Organizations may insist that their members sign in with a second factor.
+		if organization.RequireMFA && !principal.HasMultiFactor() {
+			c.JSON(http.StatusForbidden, gin.H{"error": "This organization requires two-factor authentication"})
 			c.Abort()
 			return
 		}
 
+		c.Set("organization", organization)
+		c.Set("membership", membership)
 		c.Next()
 	}
 }
@@ -3,6 +3,7 @@ package routes
 import (
 	"assessment/pkg/api/handlers"
 	"assessment/pkg/api/middleware"
+	"assessment/pkg/auth"
 
 	"github.com/gin-gonic/gin"
 )
@@ -10,24 +11,115 @@ import (
 // RegisterRoutes sets up the application's HTTP routes.
 func RegisterRoutes(router *gin.Engine) {
 
+	// Publish the token verification keys.
+	router.GET("/.well-known/jwks.json", handlers.GetJWKS)
+
+	// Publish the OpenID Connect provider metadata and user claims.
+	router.GET("/.well-known/openid-configuration", handlers.GetOpenIDConfiguration)
+	router.GET("/userinfo", middleware.AuthMiddleware(), middleware.RequireScope(auth.ScopeOpenID), handlers.GetUserInfo)
+	router.POST("/userinfo", middleware.AuthMiddleware(), middleware.RequireScope(auth.ScopeOpenID), handlers.GetUserInfo)
+
 	// Define authentication routes.
-	auth := router.Group("/auth")
+	authentication := router.Group("/auth")
 	{
-		auth.POST("/signup", handlers.Signup)                           // User registration
-		auth.POST("/signin", handlers.SignIn)                           // User login
-		auth.POST("/refresh-token", handlers.RefreshToken)              // Token refresh
-		auth.POST("/revoke-refresh-token", handlers.RevokeRefreshToken) // Token revocation
+		authentication.POST("/signup", handlers.Signup)                                                                                                 // User registration
+		authentication.POST("/signin", handlers.SignIn)                                                                                                 // User login
+		authentication.POST("/refresh-token", handlers.RefreshToken)                                                                                    // Token refresh
+		authentication.POST("/revoke-refresh-token", handlers.RevokeRefreshToken)                                                                       // Token revocation
+		authentication.GET("/sessions", middleware.AuthMiddleware(), middleware.RequireSession(), handlers.GetSessions)                                 // Active session listing
+		authentication.DELETE("/sessions/:id", middleware.AuthMiddleware(), middleware.RequireSession(), handlers.RevokeSession)                        // Session revocation
+		authentication.POST("/logout-all", middleware.AuthMiddleware(), middleware.RequireSession(), handlers.LogoutAll)                                // Sign out everywhere
+		authentication.POST("/verify-email", handlers.VerifyEmail)                                                                                      // Email verification
+		authentication.POST("/resend-verification", middleware.AuthMiddleware(), middleware.RequireSession(), handlers.ResendVerification)              // Verification email resend
+		authentication.POST("/forgot-password", handlers.ForgotPassword)                                                                                // Password reset request
+		authentication.POST("/reset-password", handlers.ResetPassword)                                                                                  // Password reset
+		authentication.POST("/change-password", middleware.AuthMiddleware(), middleware.RequireSession(), handlers.ChangePassword)                      // Password change
+		authentication.POST("/change-email", middleware.AuthMiddleware(), middleware.RequireSession(), handlers.ChangeEmail)                            // Email change request
+		authentication.POST("/confirm-email-change", handlers.ConfirmEmailChange)                                                                       // Email change confirmation
+		authentication.POST("/mfa/totp/setup", middleware.AuthMiddleware(), middleware.RequireSession(), handlers.SetupTOTP)                            // Authenticator enrollment
+		authentication.POST("/mfa/totp/confirm", middleware.AuthMiddleware(), middleware.RequireSession(), handlers.ConfirmTOTP)                        // Two-factor activation
+		authentication.POST("/mfa/totp/disable", middleware.AuthMiddleware(), middleware.RequireSession(), handlers.DisableTOTP)                        // Two-factor deactivation
+		authentication.POST("/mfa/verify", handlers.VerifyMFA)                                                                                          // Second sign-in step
+		authentication.POST("/webauthn/register/begin", middleware.AuthMiddleware(), middleware.RequireSession(), handlers.BeginWebAuthnRegistration)   // Passkey registration options
+		authentication.POST("/webauthn/register/finish", middleware.AuthMiddleware(), middleware.RequireSession(), handlers.FinishWebAuthnRegistration) // Passkey registration
+		authentication.POST("/webauthn/login/begin", handlers.BeginWebAuthnLogin)                                                                       // Passkey sign-in options
+		authentication.POST("/webauthn/login/finish", handlers.FinishWebAuthnLogin)                                                                     // Passkey sign-in
+		authentication.POST("/magic-link", handlers.RequestMagicLink)                                                                                   // Magic link request
+		authentication.GET("/magic-link/consume", handlers.ConsumeMagicLink)                                                                            // Magic link sign-in
+		authentication.POST("/magic-link/consume", handlers.ConsumeMagicLink)                                                                           // Magic link sign-in
+		authentication.GET("/oidc/:provider/login", handlers.BeginOIDCLogin)                                                                            // External identity provider sign-in
+		authentication.GET("/oidc/:provider/callback", handlers.OIDCCallback)                                                                           // External identity provider callback
 	}
 
 	// Define organization routes, secured with authentication.
 	organization := router.Group("/api")
 	organization.Use(middleware.AuthMiddleware())
 	{
-		organization.POST("organization", handlers.CreateOrganization)                                                  // Organization creation
-		organization.GET("/organization/:organization_id", middleware.InviteMiddleware(), handlers.GetOrganizationById) // Organization retrieval with invitation check
-		organization.GET("/organization", handlers.GetAllOrganizations)                                                 // All organizations retrieval
-		organization.PUT("/organization/:organization_id", handlers.UpdateOrganization)                                 // Organization update
-		organization.DELETE("/organization/:organization_id", handlers.DeleteOrganization)                              // Organization deletion
-		organization.POST("/organization/:organization_id/invite", handlers.InviteUserToOrganization)                   // Organization invitation
+		organization.POST("organization", middleware.RequireScope(auth.ScopeOrgCreate), handlers.CreateOrganization)                                                                                           // Organization creation
+		organization.GET("/organization", middleware.RequireScope(auth.PermissionOrgRead), handlers.GetAllOrganizations)                                                                                       // Caller's organizations retrieval
+		organization.POST("/invitations/:token/accept", middleware.RequireSession(), handlers.AcceptInvitation)                                                                                                // Invitation acceptance
+		organization.POST("/invitations/:token/decline", middleware.RequireSession(), handlers.DeclineInvitation)                                                                                              // Invitation decline
+		organization.GET("/organization/:organization_id", middleware.RequirePermission(auth.PermissionOrgRead), handlers.GetOrganizationById)                                                                 // Organization retrieval
+		organization.PUT("/organization/:organization_id", middleware.RequirePermission(auth.PermissionOrgUpdate), handlers.UpdateOrganization)                                                                // Organization update
+		organization.PUT("/organization/:organization_id/security", middleware.RequireSession(), middleware.RequirePermission(auth.PermissionSettingsWrite), handlers.UpdateSecuritySettings)                  // Security settings update
+		organization.DELETE("/organization/:organization_id", middleware.RequirePermission(auth.PermissionOrgDelete), handlers.DeleteOrganization)                                                             // Organization deletion
+		organization.POST("/organization/:organization_id/invite", middleware.RequirePermission(auth.PermissionMembersInvite), handlers.InviteUserToOrganization)                                              // Organization invitation
+		organization.GET("/organization/:organization_id/invitations", middleware.RequirePermission(auth.PermissionMembersInvite), handlers.GetOrganizationInvitations)                                        // Invitation listing
+		organization.DELETE("/organization/:organization_id/invitations/:invitation_id", middleware.RequirePermission(auth.PermissionMembersInvite), handlers.RevokeInvitation)                                // Invitation revocation
+		organization.GET("/organization/:organization_id/members", middleware.RequirePermission(auth.PermissionOrgRead), handlers.GetOrganizationMembers)                                                      // Member listing
+		organization.PUT("/organization/:organization_id/members/:user_id", middleware.RequirePermission(auth.PermissionMembersInvite), handlers.UpdateOrganizationMember)                                     // Member role change
+		organization.DELETE("/organization/:organization_id/members/:user_id", middleware.RequirePermission(auth.PermissionMembersRemove), handlers.RemoveOrganizationMember)                                  // Member removal
+		organization.GET("/organization/:organization_id/roles", middleware.RequirePermission(auth.PermissionOrgRead), handlers.GetOrganizationRoles)                                                          // Role listing
+		organization.POST("/organization/:organization_id/roles", middleware.RequirePermission(auth.PermissionSettingsWrite), handlers.CreateOrganizationRole)                                                 // Role creation
+		organization.PUT("/organization/:organization_id/roles/:role_name", middleware.RequirePermission(auth.PermissionSettingsWrite), handlers.UpdateOrganizationRole)                                       // Role update
+		organization.DELETE("/organization/:organization_id/roles/:role_name", middleware.RequirePermission(auth.PermissionSettingsWrite), handlers.DeleteOrganizationRole)                                    // Role deletion
+		organization.POST("/organization/:organization_id/api-keys", middleware.RequireSession(), middleware.RequirePermission(auth.PermissionSettingsWrite), handlers.CreateAPIKey)                           // API key creation
+		organization.GET("/organization/:organization_id/api-keys", middleware.RequireSession(), middleware.RequirePermission(auth.PermissionSettingsWrite), handlers.GetAPIKeys)                              // API key listing
+		organization.GET("/organization/:organization_id/api-keys/:key_id", middleware.RequireSession(), middleware.RequirePermission(auth.PermissionSettingsWrite), handlers.GetAPIKey)                       // API key retrieval
+		organization.PUT("/organization/:organization_id/api-keys/:key_id", middleware.RequireSession(), middleware.RequirePermission(auth.PermissionSettingsWrite), handlers.UpdateAPIKey)                    // API key update
+		organization.DELETE("/organization/:organization_id/api-keys/:key_id", middleware.RequireSession(), middleware.RequirePermission(auth.PermissionSettingsWrite), handlers.DeleteAPIKey)                 // API key revocation
+		organization.POST("/organization/:organization_id/oauth-clients", middleware.RequireSession(), middleware.RequirePermission(auth.PermissionSettingsWrite), handlers.CreateOAuthClient)                 // OAuth client registration
+		organization.GET("/organization/:organization_id/oauth-clients", middleware.RequireSession(), middleware.RequirePermission(auth.PermissionSettingsWrite), handlers.GetOAuthClients)                    // OAuth client listing
+		organization.GET("/organization/:organization_id/oauth-clients/:client_id", middleware.RequireSession(), middleware.RequirePermission(auth.PermissionSettingsWrite), handlers.GetOAuthClient)          // OAuth client retrieval
+		organization.PUT("/organization/:organization_id/oauth-clients/:client_id", middleware.RequireSession(), middleware.RequirePermission(auth.PermissionSettingsWrite), handlers.UpdateOAuthClient)       // OAuth client update
+		organization.DELETE("/organization/:organization_id/oauth-clients/:client_id", middleware.RequireSession(), middleware.RequirePermission(auth.PermissionSettingsWrite), handlers.DeleteOAuthClient)    // OAuth client deletion
+		organization.GET("/organization/:organization_id/saml", middleware.RequireSession(), middleware.RequirePermission(auth.PermissionSettingsWrite), handlers.GetSAMLSettings)                             // SAML settings retrieval
+		organization.PUT("/organization/:organization_id/saml", middleware.RequireSession(), middleware.RequirePermission(auth.PermissionSettingsWrite), handlers.ConfigureSAML)                               // SAML identity provider configuration
+		organization.DELETE("/organization/:organization_id/saml", middleware.RequireSession(), middleware.RequirePermission(auth.PermissionSettingsWrite), handlers.DeleteSAMLSettings)                       // SAML identity provider removal
+		organization.POST("/organization/:organization_id/saml/link", middleware.RequireSession(), middleware.RequirePermission(auth.PermissionOrgRead), handlers.LinkSAMLAccount)                             // Single sign-on account linking
+		organization.GET("/organization/:organization_id/domains", middleware.RequireSession(), middleware.RequirePermission(auth.PermissionSettingsWrite), handlers.GetOrganizationDomains)                   // Domain listing
+		organization.POST("/organization/:organization_id/domains", middleware.RequireSession(), middleware.RequirePermission(auth.PermissionSettingsWrite), handlers.AddOrganizationDomain)                   // Domain claim
+		organization.POST("/organization/:organization_id/domains/:domain/verify", middleware.RequireSession(), middleware.RequirePermission(auth.PermissionSettingsWrite), handlers.VerifyOrganizationDomain) // Domain verification
+		organization.DELETE("/organization/:organization_id/domains/:domain", middleware.RequireSession(), middleware.RequirePermission(auth.PermissionSettingsWrite), handlers.DeleteOrganizationDomain)      // Domain removal
+		organization.POST("/me/tokens", middleware.RequireSession(), handlers.CreatePersonalToken)                                                                                                             // Personal access token creation
+		organization.GET("/me/tokens", middleware.RequireSession(), handlers.GetPersonalTokens)                                                                                                                // Personal access token listing
+		organization.GET("/me/tokens/:token_id", middleware.RequireSession(), handlers.GetPersonalToken)                                                                                                       // Personal access token retrieval
+		organization.PUT("/me/tokens/:token_id", middleware.RequireSession(), handlers.UpdatePersonalToken)                                                                                                    // Personal access token update
+		organization.DELETE("/me/tokens/:token_id", middleware.RequireSession(), handlers.DeletePersonalToken)                                                                                                 // Personal access token revocation
+	}
+
+	// Define OAuth 2.0 authorization server routes. Consent is given from a signed-in session.
+	oauth := router.Group("/oauth")
+	{
+		oauth.GET("/authorize", middleware.AuthMiddleware(), middleware.RequireSession(), handlers.GetAuthorization) // Consent details
+		oauth.POST("/authorize", middleware.AuthMiddleware(), middleware.RequireSession(), handlers.Authorize)       // Consent decision
+		oauth.POST("/token", handlers.OAuthToken)                                                                    // Token issuance
+	}
+
+	// Define SAML 2.0 single sign-on routes of organizations.
+	saml := router.Group("/saml")
+	{
+		saml.GET("/:org_id/metadata", handlers.GetSAMLMetadata)   // Service provider metadata
+		saml.GET("/:org_id/login", handlers.BeginSAMLLogin)       // Single sign-on start
+		saml.POST("/:org_id/acs", handlers.SAMLAssertionConsumer) // Assertion consumer service
+	}
+
+	// Define administrative routes, restricted to the configured operators.
+	admin := router.Group("/api/admin")
+	admin.Use(middleware.AuthMiddleware(), middleware.RequireSession(), middleware.RequireAdmin())
+	{
+		admin.POST("/unlock-account", handlers.UnlockAccount)                                 // Account lockout removal
+		admin.GET("/organizations/ownerless", handlers.GetOrganizationsWithoutOwner)          // Ownerless organization listing
+		admin.POST("/organizations/:organization_id/owner", handlers.AssignOrganizationOwner) // Organization owner assignment
 	}
 }
@@ -1,8 +1,13 @@
 package pkg
 
 import (
+	"assessment/config"
 	"assessment/pkg/api/routes"
 	db "assessment/pkg/database"
+	"assessment/pkg/database/mongodb/repository"
+	"assessment/pkg/utils"
+	"fmt"
+	"log"
 
 	"github.com/gin-gonic/gin"
 )
@@ -10,10 +15,62 @@ import (
 // Run starts the web server and registers the API routes.
 func Run() {
 	// Initialize the Gin router with default middleware.
-	router := gin.Default()
+	appConfig := config.GetAppConfig()
+	router, err := newRouter(appConfig)
+	if err != nil {
+		panic(err)
+	}
 
 	// Connect to the database.
-	err := db.Connect()
+	err = db.Connect()
+	if err != nil {
+		panic(err)
+	}
+
+	// Make sure email addresses, memberships and token hashes stay unique.
+	err = repository.NewUserRepo().EnsureIndexes()
+	if err != nil {
+		log.Printf("Failed to ensure user indexes: %v", err)
+	}
+	err = repository.NewMembershipRepo().MigrateLegacyMembers()
+	if err != nil {
+		log.Printf("Failed to migrate organization members: %v", err)
+	}
+	err = repository.NewMembershipRepo().EnsureIndexes()
+	if err != nil {
+		log.Printf("Failed to ensure membership indexes: %v", err)
+	}
+	logOrganizationsWithoutOwner()
+	err = repository.NewPersonalTokenRepo().EnsureIndexes()
+	if err != nil {
+		log.Printf("Failed to ensure personal access token indexes: %v", err)
+	}
+	err = repository.NewAPIKeyRepo().EnsureIndexes()
+	if err != nil {
+		log.Printf("Failed to ensure API key indexes: %v", err)
+	}
+	err = repository.NewExternalIdentityRepo().EnsureIndexes()
+	if err != nil {
+		log.Printf("Failed to ensure external identity indexes: %v", err)
+	}
+
+	// Set up token signing and validation.
+	err = utils.InitTokens(appConfig.JWT)
+	if err != nil {
+		panic(err)
+	}
+
+	// Set up password hashing.
+	err = utils.InitPasswords(appConfig.Password)
+	if err != nil {
+		panic(err)
+	}
+
+	// Set up outgoing email.
+	utils.InitMailer(appConfig.Mail)
+
+	// Set up sign-in with external identity providers.
+	err = utils.InitOIDCProviders(appConfig.OIDCProviders, appConfig.BaseURL)
 	if err != nil {
 		panic(err)
 	}
@@ -24,3 +81,29 @@ func Run() {
 	// Start the web server on port  8080.
 	router.Run(":8080")
 }
+
+// newRouter creates the Gin router with default middleware. Only the configured proxies may
+// report the client address in X-Forwarded-For; otherwise clients could pick their own address
+// to get past API key IP restrictions and per-IP sign-in limits.
+func newRouter(cfg config.AppConfig) (*gin.Engine, error) {
+	router := gin.Default()
+	err := router.SetTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies: %v", err)
+	}
+
+	return router, nil
+}
+
+// logOrganizationsWithoutOwner reports the organizations nobody can manage, such as those
+// migrated from before memberships existed, so an administrator can assign them an owner.
+func logOrganizationsWithoutOwner() {
+	organizations, err := repository.NewOrganizationRepo().GetOrganizationsWithoutOwner()
+	if err != nil {
+		log.Printf("Failed to look for organizations without an owner: %v", err)
+		return
+	}
+	for _, org := range organizations {
+		log.Printf("Organization %s (%s) has no owner; assign one with POST /api/admin/organizations/%s/owner", org.Id.Hex(), org.Name, org.Id.Hex())
+	}
+}
@@ -0,0 +1,89 @@
+package pkg
+
+import (
+	"assessment/config"
+	"assessment/pkg/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestNewRouterClientIP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	allowedIPs := []string{"198.51.100.7"}
+
+	tests := []struct {
+		name           string
+		trustedProxies []string
+		remoteAddr     string
+		forwardedFor   string
+		wantStatus     int
+	}{
+		{name: "direct client", remoteAddr: "198.51.100.7:1234", wantStatus: http.StatusOK},
+		{name: "spoofed header from client", remoteAddr: "203.0.113.9:1234", forwardedFor: "198.51.100.7", wantStatus: http.StatusForbidden},
+		{name: "spoofed header from untrusted proxy", trustedProxies: []string{"10.0.0.0/8"}, remoteAddr: "203.0.113.9:1234", forwardedFor: "198.51.100.7", wantStatus: http.StatusForbidden},
+		{name: "header from trusted proxy", trustedProxies: []string{"10.0.0.0/8"}, remoteAddr: "10.1.2.3:1234", forwardedFor: "198.51.100.7", wantStatus: http.StatusOK},
+		{name: "disallowed client behind trusted proxy", trustedProxies: []string{"10.0.0.1"}, remoteAddr: "10.0.0.1:1234", forwardedFor: "203.0.113.9", wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, err := newRouter(config.AppConfig{TrustedProxies: tt.trustedProxies})
+			if err != nil {
+				t.Fatalf("newRouter() error = %v", err)
+			}
+			// Mirrors the IP restriction of API keys.
+			router.GET("/", func(c *gin.Context) {
+				if !utils.IPAllowed(c.ClientIP(), allowedIPs) {
+					c.Status(http.StatusForbidden)
+					return
+				}
+				c.Status(http.StatusOK)
+			})
+
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			request.RemoteAddr = tt.remoteAddr
+			if tt.forwardedFor != "" {
+				request.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+
+			if recorder.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", recorder.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestNewRouterIgnoresRotatingForwardedFor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router, err := newRouter(config.AppConfig{})
+	if err != nil {
+		t.Fatalf("newRouter() error = %v", err)
+	}
+	// Per-IP sign-in limits count attempts by the client address.
+	seen := map[string]bool{}
+	router.POST("/auth/signin", func(c *gin.Context) {
+		seen[c.ClientIP()] = true
+	})
+
+	for _, forwardedFor := range []string{"192.0.2.1", "192.0.2.2", "192.0.2.3, 10.0.0.1"} {
+		request := httptest.NewRequest(http.MethodPost, "/auth/signin", nil)
+		request.RemoteAddr = "203.0.113.9:1234"
+		request.Header.Set("X-Forwarded-For", forwardedFor)
+		router.ServeHTTP(httptest.NewRecorder(), request)
+	}
+
+	if len(seen) != 1 || !seen["203.0.113.9"] {
+		t.Errorf("client addresses = %v, want only 203.0.113.9", seen)
+	}
+}
+
+func TestNewRouterInvalidTrustedProxy(t *testing.T) {
+	if _, err := newRouter(config.AppConfig{TrustedProxies: []string{"not an address"}}); err == nil {
+		t.Errorf("newRouter() succeeded with an invalid trusted proxy, want an error")
+	}
+}
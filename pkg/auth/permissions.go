@@ -0,0 +1,91 @@
+package auth
+
+import "assessment/pkg/database/mongodb/models"
+
+// Permissions that can be granted to a role within an organization.
+const (
+	PermissionOrgRead       = "org:read"
+	PermissionOrgUpdate     = "org:update"
+	PermissionOrgDelete     = "org:delete"
+	PermissionMembersInvite = "members:invite"
+	PermissionMembersRemove = "members:remove"
+	PermissionSettingsWrite = "settings:write"
+)
+
+// AllPermissions lists every permission a role may be granted.
+var AllPermissions = []string{
+	PermissionOrgRead,
+	PermissionOrgUpdate,
+	PermissionOrgDelete,
+	PermissionMembersInvite,
+	PermissionMembersRemove,
+	PermissionSettingsWrite,
+}
+
+// builtInRoles are the default roles shipped with every organization. They cannot be
+// changed or deleted, and custom roles may not reuse their names.
+var builtInRoles = []models.Role{
+	{Name: models.RoleOwner, Permissions: AllPermissions, BuiltIn: true},
+	{Name: models.RoleAdmin, Permissions: []string{
+		PermissionOrgRead,
+		PermissionOrgUpdate,
+		PermissionMembersInvite,
+		PermissionMembersRemove,
+		PermissionSettingsWrite,
+	}, BuiltIn: true},
+	{Name: models.RoleMember, Permissions: []string{PermissionOrgRead}, BuiltIn: true},
+	{Name: models.RoleViewer, Permissions: []string{PermissionOrgRead}, BuiltIn: true},
+}
+
+// IsValidPermission reports whether permission is one of the known permissions.
+func IsValidPermission(permission string) bool {
+	return contains(AllPermissions, permission)
+}
+
+// IsBuiltInRole reports whether name refers to one of the default roles.
+func IsBuiltInRole(name string) bool {
+	for _, role := range builtInRoles {
+		if role.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Roles returns the built-in roles followed by the custom roles of an organization.
+func Roles(org *models.Organization) []models.Role {
+	roles := append([]models.Role{}, builtInRoles...)
+	if org != nil {
+		roles = append(roles, org.Roles...)
+	}
+	return roles
+}
+
+// FindRole looks up a built-in or custom role of an organization by name.
+func FindRole(org *models.Organization, name string) *models.Role {
+	for _, role := range Roles(org) {
+		if role.Name == name {
+			return &role
+		}
+	}
+	return nil
+}
+
+// HasPermission reports whether the named role grants the permission within the organization.
+func HasPermission(org *models.Organization, roleName, permission string) bool {
+	role := FindRole(org, roleName)
+	if role == nil {
+		return false
+	}
+	return contains(role.Permissions, permission)
+}
+
+// contains reports whether value is present in values.
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
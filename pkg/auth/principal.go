@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"assessment/pkg/database/mongodb/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// principalKey is the gin.Context key under which the authenticated principal is stored.
+const principalKey = "principal"
+
+// Principal is the authenticated caller of a request. Callers using an organization API key or
+// OAuth client credentials are service principals: they have no user and act only within OrganizationId.
+type Principal struct {
+	UserId primitive.ObjectID
+	Email  string
+	Name   string
+	// SessionId is the sign-in session of the request; empty unless the user signed in to this API
+	// directly rather than through a personal access token or OAuth client.
+	SessionId string
+	// TokenId is the personal access token the request was made with, if any.
+	TokenId string
+	// APIKeyId is the organization API key the request was made with, if any.
+	APIKeyId string
+	// ClientId is the OAuth client the request was made through, if any.
+	ClientId string
+	// OrganizationId is the organization a service principal is bound to.
+	OrganizationId primitive.ObjectID
+	// EmailVerified is set once the user has confirmed their email address.
+	EmailVerified bool
+	// AuthMethods lists how the user authenticated the session, see models.AuthMethodPassword.
+	AuthMethods []string
+	// Scopes restricts what the credential may be used for; nil means the full access of the user.
+	Scopes []string
+}
+
+// HasScope reports whether the principal's credential grants the scope.
+func (p *Principal) HasScope(scope string) bool {
+	if p.Scopes == nil {
+		return true
+	}
+	return contains(p.Scopes, scope)
+}
+
+// IsService reports whether the caller is an integration acting for an organization rather than a user.
+func (p *Principal) IsService() bool {
+	return !p.OrganizationId.IsZero()
+}
+
+// HasMultiFactor reports whether the session was authenticated with a second factor.
+func (p *Principal) HasMultiFactor() bool {
+	return contains(p.AuthMethods, models.AuthMethodOTP) || contains(p.AuthMethods, models.AuthMethodMultiFactor)
+}
+
+// SetPrincipal stores the authenticated principal in the context.
+func SetPrincipal(c *gin.Context, principal *Principal) {
+	c.Set(principalKey, principal)
+}
+
+// PrincipalFrom returns the authenticated principal stored by AuthMiddleware, or nil
+// when the request is not authenticated.
+func PrincipalFrom(c *gin.Context) *Principal {
+	value, exists := c.Get(principalKey)
+	if !exists {
+		return nil
+	}
+	principal, _ := value.(*Principal)
+	return principal
+}
@@ -0,0 +1,34 @@
+package auth
+
+// Scopes that can be granted to a personal access token. A token may use an organization
+// permission only when it holds the scope of the same name and the user's role grants it.
+const (
+	// ScopeOrgCreate allows creating organizations.
+	ScopeOrgCreate = "org:create"
+)
+
+// AllScopes lists every scope a token may be granted.
+var AllScopes = append([]string{ScopeOrgCreate}, AllPermissions...)
+
+// IsValidScope reports whether scope is one of the known scopes.
+func IsValidScope(scope string) bool {
+	return contains(AllScopes, scope)
+}
+
+// OpenID Connect scopes an OAuth client may request besides the scopes above. They release
+// claims about the user in ID tokens and at the userinfo endpoint.
+const (
+	ScopeOpenID  = "openid"
+	ScopeProfile = "profile"
+	ScopeEmail   = "email"
+	// ScopeOrgs releases the user's organization memberships.
+	ScopeOrgs = "orgs"
+)
+
+// OpenIDScopes lists every OpenID Connect scope.
+var OpenIDScopes = []string{ScopeOpenID, ScopeProfile, ScopeEmail, ScopeOrgs}
+
+// IsValidClientScope reports whether an OAuth client may be registered for the scope.
+func IsValidClientScope(scope string) bool {
+	return IsValidScope(scope) || contains(OpenIDScopes, scope)
+}
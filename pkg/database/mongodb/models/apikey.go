@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// structs for organization API keys
+
+// APIKey lets an integration act on behalf of one organization rather than a user.
+// Only the hash of the key is stored; the prefix is kept to tell keys apart.
+type APIKey struct {
+	Id             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	OrganizationId primitive.ObjectID `bson:"organization_id" json:"organization_id"`
+	Name           string             `bson:"name" json:"name"`
+	Prefix         string             `bson:"prefix" json:"prefix"`
+	KeyHash        string             `bson:"key_hash" json:"-"`
+	Permissions    []string           `bson:"permissions" json:"permissions"`
+	// AllowedIPs are the addresses and CIDR ranges the key may be used from; empty allows any.
+	AllowedIPs []string           `bson:"allowed_ips,omitempty" json:"allowed_ips,omitempty"`
+	CreatedBy  primitive.ObjectID `bson:"created_by" json:"created_by"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	ExpiresAt  *time.Time         `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+	LastUsedAt *time.Time         `bson:"last_used_at,omitempty" json:"last_used_at,omitempty"`
+	LastUsedIP string             `bson:"last_used_ip,omitempty" json:"last_used_ip,omitempty"`
+}
+
+type APIKeyRequest struct {
+	Name        string     `json:"name" binding:"required"`
+	Permissions []string   `json:"permissions" binding:"required"`
+	AllowedIPs  []string   `json:"allowed_ips,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+// APIKeyUpdateRequest changes the fields that are present. An empty allowed_ips list lifts the IP restriction.
+type APIKeyUpdateRequest struct {
+	Name        string   `json:"name,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+	AllowedIPs  []string `json:"allowed_ips,omitempty"`
+}
+
+// APIKeyResponse carries a new key, which is only ever shown in this response.
+type APIKeyResponse struct {
+	APIKey
+	Key string `json:"key"`
+}
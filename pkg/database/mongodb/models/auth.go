@@ -16,3 +16,35 @@ type AuthResponse struct {
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token"`
 }
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+type ChangeEmailRequest struct {
+	NewEmail string `json:"new_email" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type ConfirmEmailChangeRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type MagicLinkRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+type MagicLinkResponse struct {
+	Message     string `json:"message"`
+	DeviceNonce string `json:"device_nonce"`
+}
+
+type ConsumeMagicLinkRequest struct {
+	Token string `json:"token" form:"token" binding:"required"`
+	Nonce string `json:"nonce,omitempty" form:"nonce"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// structs for identities at external OpenID Connect providers
+
+// ExternalIdentity links the account of a user at an identity provider, identified by the
+// provider's subject, to a local user.
+type ExternalIdentity struct {
+	Id          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	UserId      primitive.ObjectID `bson:"user_id" json:"-"`
+	Provider    string             `bson:"provider" json:"provider"`
+	Subject     string             `bson:"subject" json:"subject"`
+	Email       string             `bson:"email,omitempty" json:"email,omitempty"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	LastLoginAt time.Time          `bson:"last_login_at" json:"last_login_at"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// structs for organization invitations
+
+// Statuses an invitation moves through.
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationDeclined = "declined"
+	InvitationRevoked  = "revoked"
+	InvitationExpired  = "expired"
+)
+
+type Invitation struct {
+	Id             primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	OrganizationId primitive.ObjectID `bson:"organization_id" json:"organization_id"`
+	Email          string             `bson:"email" json:"email"`
+	Role           string             `bson:"role" json:"role"`
+	Status         string             `bson:"status" json:"status"`
+	InvitedBy      primitive.ObjectID `bson:"invited_by" json:"invited_by"`
+	// InvitedByAPIKey or InvitedByClient is set instead of InvitedBy when an integration sent the invitation.
+	InvitedByAPIKey *primitive.ObjectID `bson:"invited_by_api_key,omitempty" json:"invited_by_api_key,omitempty"`
+	InvitedByClient string              `bson:"invited_by_client,omitempty" json:"invited_by_client,omitempty"`
+	CreatedAt       time.Time           `bson:"created_at" json:"created_at"`
+	ExpiresAt       time.Time           `bson:"expires_at" json:"expires_at"`
+	RespondedAt     *time.Time          `bson:"responded_at,omitempty" json:"responded_at,omitempty"`
+}
+
+type InvitationResponse struct {
+	InvitationId string    `json:"invitation_id"`
+	InviteToken  string    `json:"invite_token"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// structs for organization membership
+
+// Built-in roles every organization offers; organizations may define more.
+const (
+	RoleOwner  = "owner"
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+	RoleViewer = "viewer"
+)
+
+type Membership struct {
+	Id             primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	UserId         primitive.ObjectID `bson:"user_id" json:"user_id"`
+	OrganizationId primitive.ObjectID `bson:"organization_id" json:"organization_id"`
+	Role           string             `bson:"role" json:"role"`
+	JoinedAt       time.Time          `bson:"joined_at" json:"joined_at"`
+	InvitedBy      primitive.ObjectID `bson:"invited_by,omitempty" json:"invited_by,omitempty"`
+}
+
+// MemberResponse describes a member of an organization.
+type MemberResponse struct {
+	UserId   primitive.ObjectID `json:"user_id"`
+	Name     string             `json:"name,omitempty"`
+	Email    string             `json:"email,omitempty"`
+	Role     string             `json:"role"`
+	JoinedAt time.Time          `json:"joined_at"`
+}
+
+// MemberRoleUpdate changes the role of a member of an organization.
+type MemberRoleUpdate struct {
+	Role string `json:"role" binding:"required"`
+}
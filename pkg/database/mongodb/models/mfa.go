@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// structs for two-factor authentication
+
+// TOTPSettings holds the authenticator app enrollment of a user. Recovery codes are stored hashed.
+type TOTPSettings struct {
+	Secret        string     `bson:"secret"`
+	Enabled       bool       `bson:"enabled"`
+	EnabledAt     *time.Time `bson:"enabled_at,omitempty"`
+	LastUsedStep  int64      `bson:"last_used_step"`
+	RecoveryCodes []string   `bson:"recovery_codes,omitempty"`
+}
+
+type TOTPSetupResponse struct {
+	Secret     string `json:"secret"`
+	OtpauthURI string `json:"otpauth_uri"`
+}
+
+type TOTPCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type DisableTOTPRequest struct {
+	Password     string `json:"password" binding:"required"`
+	Code         string `json:"code,omitempty"`
+	RecoveryCode string `json:"recovery_code,omitempty"`
+}
+
+type RecoveryCodesResponse struct {
+	Message       string   `json:"message"`
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type MFAChallengeResponse struct {
+	Message     string `json:"message"`
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+}
+
+type MFAVerifyRequest struct {
+	MFAToken     string `json:"mfa_token" binding:"required"`
+	Code         string `json:"code,omitempty"`
+	RecoveryCode string `json:"recovery_code,omitempty"`
+}
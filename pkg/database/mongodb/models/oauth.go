@@ -0,0 +1,128 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// structs for the OAuth 2.0 authorization server
+
+// OAuth grant types. Clients are registered for the authorization code or client credentials
+// grant; refresh tokens are issued with the authorization code grant.
+const (
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeRefreshToken      = "refresh_token"
+	GrantTypeClientCredentials = "client_credentials"
+)
+
+// OAuthClient is a third-party application registered by an organization. Its ID is the
+// client_id. Public clients, such as single-page and native apps, have no secret.
+type OAuthClient struct {
+	Id             primitive.ObjectID `bson:"_id,omitempty" json:"client_id"`
+	OrganizationId primitive.ObjectID `bson:"organization_id" json:"organization_id"`
+	Name           string             `bson:"name" json:"name"`
+	SecretHash     string             `bson:"secret_hash,omitempty" json:"-"`
+	Public         bool               `bson:"public" json:"public"`
+	RedirectURIs   []string           `bson:"redirect_uris" json:"redirect_uris"`
+	GrantTypes     []string           `bson:"grant_types" json:"grant_types"`
+	// Scopes are the most the client may ask for; with client credentials it acts with these
+	// permissions in its own organization.
+	Scopes    []string           `bson:"scopes" json:"scopes"`
+	CreatedBy primitive.ObjectID `bson:"created_by" json:"created_by"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// AllowsGrant reports whether the client is registered for the grant type. Refresh tokens
+// come with the authorization code grant.
+func (client *OAuthClient) AllowsGrant(grantType string) bool {
+	if grantType == GrantTypeRefreshToken {
+		grantType = GrantTypeAuthorizationCode
+	}
+	for _, g := range client.GrantTypes {
+		if g == grantType {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsRedirectURI reports whether redirectURI exactly matches one registered for the client.
+func (client *OAuthClient) AllowsRedirectURI(redirectURI string) bool {
+	for _, uri := range client.RedirectURIs {
+		if uri == redirectURI {
+			return true
+		}
+	}
+	return false
+}
+
+type OAuthClientRequest struct {
+	Name         string   `json:"name" binding:"required"`
+	Public       bool     `json:"public"`
+	RedirectURIs []string `json:"redirect_uris"`
+	GrantTypes   []string `json:"grant_types" binding:"required"`
+	Scopes       []string `json:"scopes" binding:"required"`
+}
+
+type OAuthClientUpdateRequest struct {
+	Name         string   `json:"name,omitempty"`
+	RedirectURIs []string `json:"redirect_uris,omitempty"`
+	Scopes       []string `json:"scopes,omitempty"`
+}
+
+// OAuthClientResponse carries the secret of a new confidential client, which is only ever shown in this response.
+type OAuthClientResponse struct {
+	OAuthClient
+	ClientSecret string `json:"client_secret,omitempty"`
+}
+
+// AuthorizationRequest holds the parameters of the authorization endpoint, read from the query
+// string when the consent screen is prepared and from the body when the user decides.
+type AuthorizationRequest struct {
+	ResponseType        string `json:"response_type" form:"response_type"`
+	ClientId            string `json:"client_id" form:"client_id"`
+	RedirectURI         string `json:"redirect_uri" form:"redirect_uri"`
+	Scope               string `json:"scope" form:"scope"`
+	State               string `json:"state" form:"state"`
+	CodeChallenge       string `json:"code_challenge" form:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method" form:"code_challenge_method"`
+	// Nonce is echoed in the ID token so the client can tie it to its request.
+	Nonce   string `json:"nonce" form:"nonce"`
+	Approve bool   `json:"approve" form:"approve"`
+}
+
+// ConsentResponse describes what the user is asked to approve.
+type ConsentResponse struct {
+	ClientId    string   `json:"client_id"`
+	ClientName  string   `json:"client_name"`
+	Scopes      []string `json:"scopes"`
+	RedirectURI string   `json:"redirect_uri"`
+}
+
+// AuthorizationResponse tells the user agent where to send the user after the decision.
+type AuthorizationResponse struct {
+	RedirectTo string `json:"redirect_to"`
+}
+
+// TokenRequest holds the form parameters of the token endpoint.
+type TokenRequest struct {
+	GrantType    string `form:"grant_type"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	RefreshToken string `form:"refresh_token"`
+	Scope        string `form:"scope"`
+	ClientId     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
+
+// TokenResponse is the successful response of the token endpoint (RFC 6749 section 5.1).
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope"`
+	IDToken      string `json:"id_token,omitempty"`
+}
@@ -1,20 +1,50 @@
 package models
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 // structs for organization
 
 type Organization struct {
-	Id           primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
-	Name         string             `bson:"name,omitempty" json:"name,omitempty" validate:"required"`
-	Description  string             `bson:"description,omitempty" json:"description,omitempty" validate:"required"`
-	InvitedUsers []string           `bson:"invited_users,omitempty" json:"invited_users,omitempty"`
+	Id          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Name        string             `bson:"name,omitempty" json:"name,omitempty" validate:"required"`
+	Description string             `bson:"description,omitempty" json:"description,omitempty" validate:"required"`
+	Roles       []Role             `bson:"roles,omitempty" json:"roles,omitempty"`
+	CreatedBy   primitive.ObjectID `bson:"created_by,omitempty" json:"created_by,omitempty"`
+	CreatedAt   time.Time          `bson:"created_at,omitempty" json:"created_at,omitempty"`
+	RequireMFA  bool               `bson:"require_mfa,omitempty" json:"require_mfa,omitempty"`
+	// EnforceSSO refuses password sign-in to members, who must sign in with SAML instead.
+	EnforceSSO bool          `bson:"enforce_sso,omitempty" json:"enforce_sso,omitempty"`
+	SAML       *SAMLSettings `bson:"saml,omitempty" json:"-"`
+	// Domains are the email domains the organization claims for single sign-on.
+	Domains []OrganizationDomain `bson:"domains,omitempty" json:"-"`
 }
 type OrganizationUpdate struct {
 	Name        string `json:"name,omitempty" validate:"required"`
 	Description string `json:"description,omitempty" validate:"required"`
 }
 
+// SecuritySettingsUpdate changes the security settings of an organization; omitted settings are left as they are.
+type SecuritySettingsUpdate struct {
+	RequireMFA *bool `json:"require_mfa,omitempty"`
+	EnforceSSO *bool `json:"enforce_sso,omitempty"`
+}
+
+// SecuritySettingsResponse holds the security settings of an organization.
+type SecuritySettingsResponse struct {
+	RequireMFA bool `json:"require_mfa"`
+	EnforceSSO bool `json:"enforce_sso"`
+}
+
+// AssignOwnerRequest names the user an administrator makes the owner of an organization.
+type AssignOwnerRequest struct {
+	UserEmail string `json:"user_email" binding:"required,email"`
+}
+
 type InviterequestBody struct {
 	UserEmail string `json:"user_email" binding:"required,email"`
+	Role      string `json:"role,omitempty"`
 }
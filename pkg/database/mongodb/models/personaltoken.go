@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// structs for personal access tokens
+
+// PersonalAccessToken is a long-lived credential a user creates for scripts and CI.
+// Only the hash of the token is stored; the prefix is kept to tell tokens apart.
+type PersonalAccessToken struct {
+	Id        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserId    primitive.ObjectID `bson:"user_id" json:"-"`
+	Name      string             `bson:"name" json:"name"`
+	Prefix    string             `bson:"prefix" json:"prefix"`
+	TokenHash string             `bson:"token_hash" json:"-"`
+	Scopes    []string           `bson:"scopes" json:"scopes"`
+	// AuthMethods are those of the session that created the token, so organizations
+	// requiring two-factor authentication accept tokens created after passing it.
+	AuthMethods []string   `bson:"auth_methods" json:"-"`
+	CreatedAt   time.Time  `bson:"created_at" json:"created_at"`
+	ExpiresAt   *time.Time `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+	LastUsedAt  *time.Time `bson:"last_used_at,omitempty" json:"last_used_at,omitempty"`
+	LastUsedIP  string     `bson:"last_used_ip,omitempty" json:"last_used_ip,omitempty"`
+}
+
+type PersonalAccessTokenRequest struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+type PersonalAccessTokenUpdateRequest struct {
+	Name   string   `json:"name,omitempty"`
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// PersonalAccessTokenResponse carries a new token, which is only ever shown in this response.
+type PersonalAccessTokenResponse struct {
+	PersonalAccessToken
+	Token string `json:"token"`
+}
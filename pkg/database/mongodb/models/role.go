@@ -0,0 +1,18 @@
+package models
+
+// structs for organization roles
+
+type Role struct {
+	Name        string   `bson:"name" json:"name"`
+	Permissions []string `bson:"permissions" json:"permissions"`
+	BuiltIn     bool     `bson:"-" json:"built_in"`
+}
+
+type RoleRequestBody struct {
+	Name        string   `json:"name" binding:"required"`
+	Permissions []string `json:"permissions" binding:"required"`
+}
+
+type RoleUpdateRequestBody struct {
+	Permissions []string `json:"permissions" binding:"required"`
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+)
+
+// structs for SAML single sign-on
+
+// SAMLSettings configure the SAML identity provider of an organization.
+type SAMLSettings struct {
+	IDPEntityID string `bson:"idp_entity_id" json:"idp_entity_id"`
+	// IDPMetadata is the metadata XML the identity provider is trusted by.
+	IDPMetadata string `bson:"idp_metadata" json:"idp_metadata"`
+	// EmailAttribute and NameAttribute name the assertion attributes mapped to the user;
+	// common attribute names are tried when they are empty.
+	EmailAttribute string `bson:"email_attribute,omitempty" json:"email_attribute,omitempty"`
+	NameAttribute  string `bson:"name_attribute,omitempty" json:"name_attribute,omitempty"`
+	// DefaultRole is given to users joining the organization by signing in.
+	DefaultRole string    `bson:"default_role" json:"default_role"`
+	UpdatedAt   time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+type SAMLSettingsRequest struct {
+	IDPMetadata    string `json:"idp_metadata" binding:"required"`
+	EmailAttribute string `json:"email_attribute,omitempty"`
+	NameAttribute  string `json:"name_attribute,omitempty"`
+	DefaultRole    string `json:"default_role,omitempty"`
+}
+
+// OrganizationDomain is an email domain claimed by an organization. Once its ownership is proven
+// with a DNS TXT record, the organization's identity provider speaks for addresses in it.
+type OrganizationDomain struct {
+	Name              string     `bson:"name" json:"name"`
+	VerificationToken string     `bson:"verification_token" json:"-"`
+	VerifiedAt        *time.Time `bson:"verified_at,omitempty" json:"verified_at,omitempty"`
+	CreatedAt         time.Time  `bson:"created_at" json:"created_at"`
+}
+
+type DomainRequest struct {
+	Domain string `json:"domain" binding:"required"`
+}
+
+// DomainResponse is a claimed domain with the DNS TXT record that proves its ownership.
+type DomainResponse struct {
+	OrganizationDomain
+	RecordName  string `json:"record_name"`
+	RecordValue string `json:"record_value"`
+}
+
+// SAMLLinkResponse holds the identity provider URL where a signed-in user links their account.
+type SAMLLinkResponse struct {
+	RedirectURL string `json:"redirect_url"`
+}
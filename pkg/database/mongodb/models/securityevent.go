@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// structs for the security audit log
+
+// Security event types.
+const (
+	SecurityEventAccountLocked   = "account_locked"
+	SecurityEventAccountUnlocked = "account_unlocked"
+	SecurityEventOwnerAssigned   = "organization_owner_assigned"
+)
+
+// SecurityEvent is an audit record of a security relevant change to an account.
+type SecurityEvent struct {
+	Id     primitive.ObjectID  `bson:"_id,omitempty" json:"id,omitempty"`
+	Type   string              `bson:"type" json:"type"`
+	UserId *primitive.ObjectID `bson:"user_id,omitempty" json:"user_id,omitempty"`
+	Email  string              `bson:"email" json:"email"`
+	IP     string              `bson:"ip,omitempty" json:"ip,omitempty"`
+	// ActorId is the user who caused the event, when it was not the account itself.
+	ActorId *primitive.ObjectID `bson:"actor_id,omitempty" json:"actor_id,omitempty"`
+	// OrganizationId is the organization the event concerns, if any.
+	OrganizationId *primitive.ObjectID `bson:"organization_id,omitempty" json:"organization_id,omitempty"`
+	CreatedAt      time.Time           `bson:"created_at" json:"created_at"`
+}
+
+type UnlockAccountRequest struct {
+	Email string `json:"email" binding:"required"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// structs for sign-in sessions
+
+// Authentication method references (RFC 8176) recorded for a session.
+const (
+	AuthMethodPassword = "pwd"
+	AuthMethodOTP      = "otp"
+	// AuthMethodHardwareKey is a passkey; together with AuthMethodMultiFactor when the
+	// authenticator also verified the user.
+	AuthMethodHardwareKey = "hwk"
+	AuthMethodMultiFactor = "mfa"
+	// AuthMethodEmailLink is a magic link sent to the user's email address.
+	AuthMethodEmailLink = "email"
+	// AuthMethodFederated is a sign-in at an external OpenID Connect provider.
+	AuthMethodFederated = "fed"
+)
+
+type Session struct {
+	Id            primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	UserId        primitive.ObjectID `bson:"user_id" json:"-"`
+	UserAgent     string             `bson:"user_agent" json:"user_agent"`
+	IP            string             `bson:"ip" json:"ip"`
+	RefreshFamily string             `bson:"refresh_family" json:"-"`
+	AuthMethods   []string           `bson:"auth_methods" json:"auth_methods"`
+	// ClientId and Scopes are set when the user authorized an OAuth client rather than signing in.
+	ClientId   string     `bson:"client_id,omitempty" json:"client_id,omitempty"`
+	Scopes     []string   `bson:"scopes,omitempty" json:"scopes,omitempty"`
+	CreatedAt  time.Time  `bson:"created_at" json:"created_at"`
+	LastUsedAt time.Time  `bson:"last_used_at" json:"last_used_at"`
+	RevokedAt  *time.Time `bson:"revoked_at,omitempty" json:"-"`
+	Current    bool       `bson:"-" json:"current"`
+}
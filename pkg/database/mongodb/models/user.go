@@ -1,10 +1,18 @@
 package models
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 type User struct {
-	Id       primitive.ObjectID `json:"id,omitempty"`
-	Name     string             `json:"name,omitempty" validate:"required"`
-	Email    string             `json:"email,omitempty" validate:"required"`
-	Password string             `json:"password,omitempty" validate:"required"`
+	Id    primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Name  string             `bson:"name,omitempty" json:"name,omitempty" validate:"required"`
+	Email string             `bson:"email,omitempty" json:"email,omitempty" validate:"required"`
+	// NormalizedEmail identifies the account, see utils.NormalizeEmail. It is unique.
+	NormalizedEmail string        `bson:"normalized_email,omitempty" json:"-"`
+	Password        string        `bson:"password,omitempty" json:"password,omitempty" validate:"required"`
+	EmailVerifiedAt *time.Time    `bson:"email_verified_at,omitempty" json:"email_verified_at,omitempty"`
+	TOTP            *TOTPSettings `bson:"totp,omitempty" json:"-"`
 }
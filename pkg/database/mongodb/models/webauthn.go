@@ -0,0 +1,101 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// structs for WebAuthn passkeys
+
+// WebAuthnCredential is a passkey registered by a user. Credential IDs are stored as unpadded base64url.
+type WebAuthnCredential struct {
+	Id           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserId       primitive.ObjectID `bson:"user_id" json:"-"`
+	CredentialId string             `bson:"credential_id" json:"credential_id"`
+	PublicKey    []byte             `bson:"public_key" json:"-"`
+	SignCount    uint32             `bson:"sign_count" json:"-"`
+	Name         string             `bson:"name" json:"name"`
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+	LastUsedAt   *time.Time         `bson:"last_used_at,omitempty" json:"last_used_at,omitempty"`
+}
+
+// Options passed to navigator.credentials.create and navigator.credentials.get.
+// Binary values are unpadded base64url, as in the WebAuthn JSON serialization.
+
+type WebAuthnRelyingPartyEntity struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type WebAuthnUserEntity struct {
+	Id          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type WebAuthnCredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int64  `json:"alg"`
+}
+
+type WebAuthnCredentialDescriptor struct {
+	Type string `json:"type"`
+	Id   string `json:"id"`
+}
+
+type WebAuthnAuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+type WebAuthnCreationOptions struct {
+	Challenge              string                         `json:"challenge"`
+	RP                     WebAuthnRelyingPartyEntity     `json:"rp"`
+	User                   WebAuthnUserEntity             `json:"user"`
+	PubKeyCredParams       []WebAuthnCredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                          `json:"timeout"`
+	Attestation            string                         `json:"attestation"`
+	ExcludeCredentials     []WebAuthnCredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection WebAuthnAuthenticatorSelection `json:"authenticatorSelection"`
+}
+
+type WebAuthnRequestOptions struct {
+	Challenge        string                         `json:"challenge"`
+	RPId             string                         `json:"rpId"`
+	Timeout          int64                          `json:"timeout"`
+	UserVerification string                         `json:"userVerification"`
+	AllowCredentials []WebAuthnCredentialDescriptor `json:"allowCredentials"`
+}
+
+type WebAuthnLoginBeginRequest struct {
+	Email string `json:"email,omitempty"`
+}
+
+type WebAuthnAttestationResponse struct {
+	ClientDataJSON    string `json:"clientDataJSON" binding:"required"`
+	AttestationObject string `json:"attestationObject" binding:"required"`
+}
+
+type WebAuthnAssertionResponse struct {
+	ClientDataJSON    string `json:"clientDataJSON" binding:"required"`
+	AuthenticatorData string `json:"authenticatorData" binding:"required"`
+	Signature         string `json:"signature" binding:"required"`
+	UserHandle        string `json:"userHandle,omitempty"`
+}
+
+// WebAuthnRegistrationRequest is the PublicKeyCredential returned by navigator.credentials.create,
+// with an optional name to tell the user's passkeys apart.
+type WebAuthnRegistrationRequest struct {
+	Name     string                      `json:"name,omitempty"`
+	Id       string                      `json:"id" binding:"required"`
+	Type     string                      `json:"type"`
+	Response WebAuthnAttestationResponse `json:"response" binding:"required"`
+}
+
+// WebAuthnLoginRequest is the PublicKeyCredential returned by navigator.credentials.get.
+type WebAuthnLoginRequest struct {
+	Id       string                    `json:"id" binding:"required"`
+	Type     string                    `json:"type"`
+	Response WebAuthnAssertionResponse `json:"response" binding:"required"`
+}
@@ -0,0 +1,196 @@
+package repository
+
+import (
+	"assessment/pkg/database"
+	"assessment/pkg/database/mongodb/models"
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// APIKeyRepo represents the MongoDB collection for organization API keys.
+type APIKeyRepo struct {
+	collection *mongo.Collection
+}
+
+// NewAPIKeyRepo initializes a new APIKeyRepo instance.
+func NewAPIKeyRepo() *APIKeyRepo {
+	db := database.GetDatabase()
+	return &APIKeyRepo{collection: db.Collection("api_keys")}
+}
+
+// EnsureIndexes creates the unique index used to look keys up by hash.
+func (repo *APIKeyRepo) EnsureIndexes() error {
+	_, err := repo.collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.M{"key_hash": 1},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// CreateAPIKey inserts a new API key and returns its ID.
+func (repo *APIKeyRepo) CreateAPIKey(key *models.APIKey) (string, error) {
+	result, err := repo.collection.InsertOne(context.Background(), key)
+	if err != nil {
+		return "", err
+	}
+
+	keyID := result.InsertedID.(primitive.ObjectID)
+	key.Id = keyID
+	return keyID.Hex(), nil
+}
+
+// GetAPIKeyByHash retrieves an unexpired API key by the hash of its secret.
+// It returns nil without an error when no key matches.
+func (repo *APIKeyRepo) GetAPIKeyByHash(keyHash string) (*models.APIKey, error) {
+	filter := bson.M{
+		"key_hash": keyHash,
+		"$or": []bson.M{
+			{"expires_at": bson.M{"$exists": false}},
+			{"expires_at": bson.M{"$gt": time.Now()}},
+		},
+	}
+
+	var key models.APIKey
+	err := repo.collection.FindOne(context.Background(), filter).Decode(&key)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+// GetAPIKey retrieves an API key of an organization by its ID.
+// It returns nil without an error when the organization has no such key.
+func (repo *APIKeyRepo) GetAPIKey(organizationID, keyID string) (*models.APIKey, error) {
+	filter, err := organizationKeyFilter(organizationID, keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	var key models.APIKey
+	err = repo.collection.FindOne(context.Background(), filter).Decode(&key)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+// GetAPIKeysByOrganization lists the API keys of an organization, newest first.
+func (repo *APIKeyRepo) GetAPIKeysByOrganization(organizationID string) ([]*models.APIKey, error) {
+	var keys []*models.APIKey
+
+	orgObjectID, err := primitive.ObjectIDFromHex(organizationID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id: %v", err)
+	}
+
+	opts := options.Find().SetSort(bson.M{"created_at": -1})
+	cursor, err := repo.collection.Find(context.Background(), bson.M{"organization_id": orgObjectID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	for cursor.Next(context.Background()) {
+		var key models.APIKey
+		err := cursor.Decode(&key)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, &key)
+	}
+
+	return keys, nil
+}
+
+// UpdateAPIKey applies the fields present in updateData to an API key of an organization.
+// It returns the updated key, or nil when the organization has no such key.
+func (repo *APIKeyRepo) UpdateAPIKey(organizationID, keyID string, updateData *models.APIKeyUpdateRequest) (*models.APIKey, error) {
+	filter, err := organizationKeyFilter(organizationID, keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := bson.M{}
+	if updateData.Name != "" {
+		fields["name"] = updateData.Name
+	}
+	if updateData.Permissions != nil {
+		fields["permissions"] = updateData.Permissions
+	}
+	if updateData.AllowedIPs != nil {
+		fields["allowed_ips"] = updateData.AllowedIPs
+	}
+
+	var key models.APIKey
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err = repo.collection.FindOneAndUpdate(context.Background(), filter, bson.M{"$set": fields}, opts).Decode(&key)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+// DeleteAPIKey revokes an API key of an organization. It reports false when the organization has no such key.
+func (repo *APIKeyRepo) DeleteAPIKey(organizationID, keyID string) (bool, error) {
+	filter, err := organizationKeyFilter(organizationID, keyID)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := repo.collection.DeleteOne(context.Background(), filter)
+	if err != nil {
+		return false, err
+	}
+
+	return result.DeletedCount == 1, nil
+}
+
+// DeleteAPIKeysByOrganization revokes every API key of an organization.
+func (repo *APIKeyRepo) DeleteAPIKeysByOrganization(organizationID string) error {
+	orgObjectID, err := primitive.ObjectIDFromHex(organizationID)
+	if err != nil {
+		return fmt.Errorf("invalid id: %v", err)
+	}
+
+	_, err = repo.collection.DeleteMany(context.Background(), bson.M{"organization_id": orgObjectID})
+	return err
+}
+
+// RecordAPIKeyUse stores when and from where an API key was last used.
+func (repo *APIKeyRepo) RecordAPIKeyUse(id primitive.ObjectID, ip string) error {
+	update := bson.M{"$set": bson.M{"last_used_at": time.Now(), "last_used_ip": ip}}
+	_, err := repo.collection.UpdateOne(context.Background(), bson.M{"_id": id}, update)
+	return err
+}
+
+// organizationKeyFilter matches a key by ID, only when it belongs to the organization.
+func organizationKeyFilter(organizationID, keyID string) (bson.M, error) {
+	orgObjectID, err := primitive.ObjectIDFromHex(organizationID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id: %v", err)
+	}
+	keyObjectID, err := primitive.ObjectIDFromHex(keyID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id: %v", err)
+	}
+
+	return bson.M{"_id": keyObjectID, "organization_id": orgObjectID}, nil
+}
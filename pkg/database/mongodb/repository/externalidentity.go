@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"assessment/pkg/database"
+	"assessment/pkg/database/mongodb/models"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrIdentityExists is returned when the account at the identity provider is already linked to a user.
+var ErrIdentityExists = errors.New("external identity already linked")
+
+// ExternalIdentityRepo represents the MongoDB collection for identities at external OpenID Connect providers.
+type ExternalIdentityRepo struct {
+	collection *mongo.Collection
+}
+
+// NewExternalIdentityRepo initializes a new ExternalIdentityRepo instance.
+func NewExternalIdentityRepo() *ExternalIdentityRepo {
+	db := database.GetDatabase()
+	return &ExternalIdentityRepo{collection: db.Collection("external_identities")}
+}
+
+// EnsureIndexes creates the unique index that links each account at a provider to one user.
+func (repo *ExternalIdentityRepo) EnsureIndexes() error {
+	_, err := repo.collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "provider", Value: 1}, {Key: "subject", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// CreateIdentity links an account at a provider to a user. It fails with ErrIdentityExists
+// when the account is already linked.
+func (repo *ExternalIdentityRepo) CreateIdentity(identity *models.ExternalIdentity) error {
+	result, err := repo.collection.InsertOne(context.Background(), identity)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrIdentityExists
+	}
+	if err != nil {
+		return err
+	}
+
+	identity.Id = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// GetIdentity retrieves the link of an account at a provider by the provider's subject.
+// It returns nil without an error when the account is not linked.
+func (repo *ExternalIdentityRepo) GetIdentity(provider, subject string) (*models.ExternalIdentity, error) {
+	var identity models.ExternalIdentity
+	err := repo.collection.FindOne(context.Background(), bson.M{"provider": provider, "subject": subject}).Decode(&identity)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &identity, nil
+}
+
+// RecordLogin stores the time of a sign-in with a linked identity and the email address the provider reported.
+func (repo *ExternalIdentityRepo) RecordLogin(identityID primitive.ObjectID, email string) error {
+	update := bson.M{"$set": bson.M{"last_login_at": time.Now(), "email": email}}
+	_, err := repo.collection.UpdateByID(context.Background(), identityID, update)
+	return err
+}
+
+// DeleteIdentitiesByProvider removes every link to accounts at a provider.
+func (repo *ExternalIdentityRepo) DeleteIdentitiesByProvider(provider string) error {
+	_, err := repo.collection.DeleteMany(context.Background(), bson.M{"provider": provider})
+	return err
+}
+
+// DeleteIdentitiesByUser removes every link of a user to accounts at providers.
+func (repo *ExternalIdentityRepo) DeleteIdentitiesByUser(userID string) error {
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return fmt.Errorf("invalid id: %v", err)
+	}
+
+	_, err = repo.collection.DeleteMany(context.Background(), bson.M{"user_id": objectID})
+	return err
+}
@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"assessment/pkg/database"
+	"assessment/pkg/database/mongodb/models"
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// InvitationRepo represents the MongoDB collection for organization invitations.
+type InvitationRepo struct {
+	collection *mongo.Collection
+}
+
+// NewInvitationRepo initializes a new InvitationRepo instance.
+func NewInvitationRepo() *InvitationRepo {
+	db := database.GetDatabase()
+	return &InvitationRepo{collection: db.Collection("invitations")}
+}
+
+// CreateInvitation inserts a new invitation and returns its ID.
+func (repo *InvitationRepo) CreateInvitation(invitation *models.Invitation) (string, error) {
+	result, err := repo.collection.InsertOne(context.Background(), invitation)
+	if err != nil {
+		return "", err
+	}
+
+	invitationID := result.InsertedID.(primitive.ObjectID).Hex()
+	return invitationID, nil
+}
+
+// GetInvitationById retrieves an invitation by its ID.
+// It returns nil without an error when no invitation matches.
+func (repo *InvitationRepo) GetInvitationById(invitationID string) (*models.Invitation, error) {
+	objectID, err := primitive.ObjectIDFromHex(invitationID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id: %v", err)
+	}
+
+	var invitation models.Invitation
+	err = repo.collection.FindOne(context.Background(), bson.M{"_id": objectID}).Decode(&invitation)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &invitation, nil
+}
+
+// GetPendingInvitation retrieves the pending, unexpired invitation of an email to an organization.
+// It returns nil without an error when there is none.
+func (repo *InvitationRepo) GetPendingInvitation(organizationID, email string) (*models.Invitation, error) {
+	orgObjectID, err := primitive.ObjectIDFromHex(organizationID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id: %v", err)
+	}
+
+	filter := bson.M{
+		"organization_id": orgObjectID,
+		"email":           email,
+		"status":          models.InvitationPending,
+		"expires_at":      bson.M{"$gt": time.Now()},
+	}
+	var invitation models.Invitation
+	err = repo.collection.FindOne(context.Background(), filter).Decode(&invitation)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &invitation, nil
+}
+
+// GetInvitationsByOrganization lists every invitation of an organization.
+func (repo *InvitationRepo) GetInvitationsByOrganization(organizationID string) ([]*models.Invitation, error) {
+	var invitations []*models.Invitation
+
+	orgObjectID, err := primitive.ObjectIDFromHex(organizationID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id: %v", err)
+	}
+
+	cursor, err := repo.collection.Find(context.Background(), bson.M{"organization_id": orgObjectID})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	for cursor.Next(context.Background()) {
+		var invitation models.Invitation
+		err := cursor.Decode(&invitation)
+		if err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, &invitation)
+	}
+
+	return invitations, nil
+}
+
+// CountPendingInvitationsByRole counts the pending invitations of an organization offering a role.
+func (repo *InvitationRepo) CountPendingInvitationsByRole(organizationID, role string) (int64, error) {
+	orgObjectID, err := primitive.ObjectIDFromHex(organizationID)
+	if err != nil {
+		return 0, fmt.Errorf("invalid id: %v", err)
+	}
+
+	filter := bson.M{"organization_id": orgObjectID, "role": role, "status": models.InvitationPending}
+	return repo.collection.CountDocuments(context.Background(), filter)
+}
+
+// UpdateInvitationStatus moves a pending invitation to a new status.
+// It reports false when the invitation was no longer pending, which makes every transition single-use.
+func (repo *InvitationRepo) UpdateInvitationStatus(invitationID, status string) (bool, error) {
+	objectID, err := primitive.ObjectIDFromHex(invitationID)
+	if err != nil {
+		return false, fmt.Errorf("invalid id: %v", err)
+	}
+
+	filter := bson.M{"_id": objectID, "status": models.InvitationPending}
+	update := bson.M{"$set": bson.M{
+		"status":       status,
+		"responded_at": time.Now(),
+	}}
+
+	result, err := repo.collection.UpdateOne(context.Background(), filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+// ExpireInvitations marks the pending invitations of an organization that are past their expiry as expired.
+func (repo *InvitationRepo) ExpireInvitations(organizationID string) error {
+	orgObjectID, err := primitive.ObjectIDFromHex(organizationID)
+	if err != nil {
+		return fmt.Errorf("invalid id: %v", err)
+	}
+
+	filter := bson.M{
+		"organization_id": orgObjectID,
+		"status":          models.InvitationPending,
+		"expires_at":      bson.M{"$lte": time.Now()},
+	}
+	update := bson.M{"$set": bson.M{"status": models.InvitationExpired}}
+
+	_, err = repo.collection.UpdateMany(context.Background(), filter, update)
+	return err
+}
+
+// UpdatePendingInvitationsEmail readdresses the pending invitations of an email to a new address.
+func (repo *InvitationRepo) UpdatePendingInvitationsEmail(oldEmail, newEmail string) error {
+	filter := bson.M{"email": oldEmail, "status": models.InvitationPending}
+	update := bson.M{"$set": bson.M{"email": newEmail}}
+
+	_, err := repo.collection.UpdateMany(context.Background(), filter, update)
+	return err
+}
+
+// DeleteInvitationsByOrganization removes every invitation of an organization.
+func (repo *InvitationRepo) DeleteInvitationsByOrganization(organizationID string) error {
+	orgObjectID, err := primitive.ObjectIDFromHex(organizationID)
+	if err != nil {
+		return fmt.Errorf("invalid id: %v", err)
+	}
+
+	_, err = repo.collection.DeleteMany(context.Background(), bson.M{"organization_id": orgObjectID})
+	return err
+}
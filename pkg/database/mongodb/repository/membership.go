@@ -0,0 +1,274 @@
+package repository
+
+import (
+	"assessment/pkg/database"
+	"assessment/pkg/database/mongodb/models"
+	"assessment/pkg/utils"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrMembershipExists is returned when the user is already a member of the organization.
+var ErrMembershipExists = errors.New("membership already exists")
+
+// MembershipRepo represents the MongoDB collection for organization memberships.
+type MembershipRepo struct {
+	collection *mongo.Collection
+}
+
+// NewMembershipRepo initializes a new MembershipRepo instance.
+func NewMembershipRepo() *MembershipRepo {
+	db := database.GetDatabase()
+	return &MembershipRepo{collection: db.Collection("memberships")}
+}
+
+// legacyOrganization holds the members of an organization created before memberships existed,
+// when access was granted to the email addresses in invited_users. Only organizations created
+// since then record their creator.
+type legacyOrganization struct {
+	Id           primitive.ObjectID `bson:"_id"`
+	CreatedBy    primitive.ObjectID `bson:"created_by,omitempty"`
+	CreatedAt    time.Time          `bson:"created_at,omitempty"`
+	InvitedUsers []string           `bson:"invited_users"`
+}
+
+// MigrateLegacyMembers moves the members of organizations created before memberships existed
+// into memberships. The creator becomes the owner and every invited user with an account a
+// member; invited addresses without an account are dropped, as they never granted access to
+// anyone. Legacy organizations never recorded their creator, so most come out without an owner;
+// OrganizationRepo.GetOrganizationsWithoutOwner finds them for an administrator to assign one.
+// It runs after UserRepo.EnsureIndexes, which fills in the normalized emails it looks up.
+func (repo *MembershipRepo) MigrateLegacyMembers() error {
+	db := database.GetDatabase()
+	organizations := db.Collection("organization")
+	users := db.Collection("user")
+
+	cursor, err := organizations.Find(context.Background(), bson.M{"invited_users": bson.M{"$exists": true}})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(context.Background())
+
+	for cursor.Next(context.Background()) {
+		var org legacyOrganization
+		if err := cursor.Decode(&org); err != nil {
+			return err
+		}
+
+		if !org.CreatedBy.IsZero() {
+			if err := repo.backfillMembership(org, org.CreatedBy, models.RoleOwner); err != nil {
+				return err
+			}
+		}
+
+		for _, email := range org.InvitedUsers {
+			normalized, err := utils.NormalizeEmail(email)
+			if err != nil {
+				log.Printf("Dropping invalid invited email %q of organization %s", email, org.Id.Hex())
+				continue
+			}
+			var user models.User
+			err = users.FindOne(context.Background(), bson.M{"normalized_email": normalized}).Decode(&user)
+			if err == mongo.ErrNoDocuments {
+				log.Printf("Dropping invited email %q of organization %s without an account", email, org.Id.Hex())
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if err := repo.backfillMembership(org, user.Id, models.RoleMember); err != nil {
+				return err
+			}
+		}
+
+		// Drop the legacy list once its members are migrated, so this only runs once.
+		_, err = organizations.UpdateOne(context.Background(), bson.M{"_id": org.Id}, bson.M{"$unset": bson.M{"invited_users": ""}})
+		if err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+// EnsureIndexes creates the unique index on the organization and user of a membership.
+func (repo *MembershipRepo) EnsureIndexes() error {
+	_, err := repo.collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "organization_id", Value: 1}, {Key: "user_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create unique membership index: %v", err)
+	}
+
+	return nil
+}
+
+// backfillMembership gives a user a role in a legacy organization, unless the user is already a member.
+func (repo *MembershipRepo) backfillMembership(org legacyOrganization, userID primitive.ObjectID, role string) error {
+	joinedAt := org.CreatedAt
+	if joinedAt.IsZero() {
+		joinedAt = org.Id.Timestamp()
+	}
+
+	filter := bson.M{"organization_id": org.Id, "user_id": userID}
+	update := bson.M{"$setOnInsert": models.Membership{UserId: userID, OrganizationId: org.Id, Role: role, JoinedAt: joinedAt}}
+	_, err := repo.collection.UpdateOne(context.Background(), filter, update, options.Update().SetUpsert(true))
+	return err
+}
+
+// CreateMembership inserts a new membership and returns its ID. It fails with ErrMembershipExists
+// when the user is already a member of the organization.
+func (repo *MembershipRepo) CreateMembership(membership *models.Membership) (string, error) {
+	result, err := repo.collection.InsertOne(context.Background(), membership)
+	if mongo.IsDuplicateKeyError(err) {
+		return "", ErrMembershipExists
+	}
+	if err != nil {
+		return "", err
+	}
+
+	membershipID := result.InsertedID.(primitive.ObjectID).Hex()
+	return membershipID, nil
+}
+
+// GetMembership retrieves the membership of a user in an organization.
+// It returns nil without an error when the user is not a member.
+func (repo *MembershipRepo) GetMembership(organizationID, userID string) (*models.Membership, error) {
+	orgObjectID, err := primitive.ObjectIDFromHex(organizationID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id: %v", err)
+	}
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id: %v", err)
+	}
+
+	filter := bson.M{"organization_id": orgObjectID, "user_id": userObjectID}
+	var membership models.Membership
+	err = repo.collection.FindOne(context.Background(), filter).Decode(&membership)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &membership, nil
+}
+
+// GetMembershipsByUser lists every organization membership held by a user.
+func (repo *MembershipRepo) GetMembershipsByUser(userID string) ([]*models.Membership, error) {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id: %v", err)
+	}
+
+	return repo.find(bson.M{"user_id": userObjectID})
+}
+
+// GetMembershipsByOrganization lists every member of an organization.
+func (repo *MembershipRepo) GetMembershipsByOrganization(organizationID string) ([]*models.Membership, error) {
+	orgObjectID, err := primitive.ObjectIDFromHex(organizationID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id: %v", err)
+	}
+
+	return repo.find(bson.M{"organization_id": orgObjectID})
+}
+
+// UpdateMembershipRole changes the role of a member of an organization. It reports false when
+// the user is not a member.
+func (repo *MembershipRepo) UpdateMembershipRole(organizationID, userID, role string) (bool, error) {
+	orgObjectID, err := primitive.ObjectIDFromHex(organizationID)
+	if err != nil {
+		return false, fmt.Errorf("invalid id: %v", err)
+	}
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return false, fmt.Errorf("invalid id: %v", err)
+	}
+
+	filter := bson.M{"organization_id": orgObjectID, "user_id": userObjectID}
+	result, err := repo.collection.UpdateOne(context.Background(), filter, bson.M{"$set": bson.M{"role": role}})
+	if err != nil {
+		return false, err
+	}
+
+	return result.MatchedCount == 1, nil
+}
+
+// CountMembershipsByRole counts the members of an organization holding a role.
+func (repo *MembershipRepo) CountMembershipsByRole(organizationID, role string) (int64, error) {
+	orgObjectID, err := primitive.ObjectIDFromHex(organizationID)
+	if err != nil {
+		return 0, fmt.Errorf("invalid id: %v", err)
+	}
+
+	filter := bson.M{"organization_id": orgObjectID, "role": role}
+	return repo.collection.CountDocuments(context.Background(), filter)
+}
+
+// DeleteMembership removes a user from an organization. It reports false when the user is not a member.
+func (repo *MembershipRepo) DeleteMembership(organizationID, userID string) (bool, error) {
+	orgObjectID, err := primitive.ObjectIDFromHex(organizationID)
+	if err != nil {
+		return false, fmt.Errorf("invalid id: %v", err)
+	}
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return false, fmt.Errorf("invalid id: %v", err)
+	}
+
+	filter := bson.M{"organization_id": orgObjectID, "user_id": userObjectID}
+	result, err := repo.collection.DeleteOne(context.Background(), filter)
+	if err != nil {
+		return false, err
+	}
+
+	return result.DeletedCount == 1, nil
+}
+
+// DeleteMembershipsByOrganization removes every membership of an organization.
+func (repo *MembershipRepo) DeleteMembershipsByOrganization(organizationID string) error {
+	orgObjectID, err := primitive.ObjectIDFromHex(organizationID)
+	if err != nil {
+		return fmt.Errorf("invalid id: %v", err)
+	}
+
+	_, err = repo.collection.DeleteMany(context.Background(), bson.M{"organization_id": orgObjectID})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// find returns all memberships matching the filter.
+func (repo *MembershipRepo) find(filter bson.M) ([]*models.Membership, error) {
+	var memberships []*models.Membership
+
+	cursor, err := repo.collection.Find(context.Background(), filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	for cursor.Next(context.Background()) {
+		var membership models.Membership
+		err := cursor.Decode(&membership)
+		if err != nil {
+			return nil, err
+		}
+		memberships = append(memberships, &membership)
+	}
+
+	return memberships, nil
+}
@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"assessment/pkg/database"
+	"assessment/pkg/database/mongodb/models"
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// OAuthClientRepo represents the MongoDB collection for registered OAuth clients.
+type OAuthClientRepo struct {
+	collection *mongo.Collection
+}
+
+// NewOAuthClientRepo initializes a new OAuthClientRepo instance.
+func NewOAuthClientRepo() *OAuthClientRepo {
+	db := database.GetDatabase()
+	return &OAuthClientRepo{collection: db.Collection("oauth_clients")}
+}
+
+// CreateClient inserts a new OAuth client and returns its ID, which is its client_id.
+func (repo *OAuthClientRepo) CreateClient(client *models.OAuthClient) (string, error) {
+	result, err := repo.collection.InsertOne(context.Background(), client)
+	if err != nil {
+		return "", err
+	}
+
+	clientID := result.InsertedID.(primitive.ObjectID)
+	client.Id = clientID
+	return clientID.Hex(), nil
+}
+
+// GetClientById retrieves an OAuth client by its client_id.
+// It returns nil without an error when no client matches.
+func (repo *OAuthClientRepo) GetClientById(clientID string) (*models.OAuthClient, error) {
+	clientObjectID, err := primitive.ObjectIDFromHex(clientID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id: %v", err)
+	}
+
+	var client models.OAuthClient
+	err = repo.collection.FindOne(context.Background(), bson.M{"_id": clientObjectID}).Decode(&client)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &client, nil
+}
+
+// GetClient retrieves an OAuth client of an organization by its ID.
+// It returns nil without an error when the organization has no such client.
+func (repo *OAuthClientRepo) GetClient(organizationID, clientID string) (*models.OAuthClient, error) {
+	filter, err := organizationClientFilter(organizationID, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	var client models.OAuthClient
+	err = repo.collection.FindOne(context.Background(), filter).Decode(&client)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &client, nil
+}
+
+// GetClientsByOrganization lists the OAuth clients of an organization, newest first.
+func (repo *OAuthClientRepo) GetClientsByOrganization(organizationID string) ([]*models.OAuthClient, error) {
+	var clients []*models.OAuthClient
+
+	orgObjectID, err := primitive.ObjectIDFromHex(organizationID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id: %v", err)
+	}
+
+	opts := options.Find().SetSort(bson.M{"created_at": -1})
+	cursor, err := repo.collection.Find(context.Background(), bson.M{"organization_id": orgObjectID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	for cursor.Next(context.Background()) {
+		var client models.OAuthClient
+		err := cursor.Decode(&client)
+		if err != nil {
+			return nil, err
+		}
+		clients = append(clients, &client)
+	}
+
+	return clients, nil
+}
+
+// UpdateClient applies the fields present in updateData to an OAuth client of an organization.
+// It returns the updated client, or nil when the organization has no such client.
+func (repo *OAuthClientRepo) UpdateClient(organizationID, clientID string, updateData *models.OAuthClientUpdateRequest) (*models.OAuthClient, error) {
+	filter, err := organizationClientFilter(organizationID, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := bson.M{}
+	if updateData.Name != "" {
+		fields["name"] = updateData.Name
+	}
+	if updateData.RedirectURIs != nil {
+		fields["redirect_uris"] = updateData.RedirectURIs
+	}
+	if updateData.Scopes != nil {
+		fields["scopes"] = updateData.Scopes
+	}
+
+	var client models.OAuthClient
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err = repo.collection.FindOneAndUpdate(context.Background(), filter, bson.M{"$set": fields}, opts).Decode(&client)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &client, nil
+}
+
+// DeleteClient removes an OAuth client of an organization. It reports false when the organization has no such client.
+func (repo *OAuthClientRepo) DeleteClient(organizationID, clientID string) (bool, error) {
+	filter, err := organizationClientFilter(organizationID, clientID)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := repo.collection.DeleteOne(context.Background(), filter)
+	if err != nil {
+		return false, err
+	}
+
+	return result.DeletedCount == 1, nil
+}
+
+// DeleteClientsByOrganization removes every OAuth client of an organization.
+func (repo *OAuthClientRepo) DeleteClientsByOrganization(organizationID string) error {
+	orgObjectID, err := primitive.ObjectIDFromHex(organizationID)
+	if err != nil {
+		return fmt.Errorf("invalid id: %v", err)
+	}
+
+	_, err = repo.collection.DeleteMany(context.Background(), bson.M{"organization_id": orgObjectID})
+	return err
+}
+
+// organizationClientFilter matches a client by ID, only when it belongs to the organization.
+func organizationClientFilter(organizationID, clientID string) (bson.M, error) {
+	orgObjectID, err := primitive.ObjectIDFromHex(organizationID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id: %v", err)
+	}
+	clientObjectID, err := primitive.ObjectIDFromHex(clientID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id: %v", err)
+	}
+
+	return bson.M{"_id": clientObjectID, "organization_id": orgObjectID}, nil
+}
@@ -5,6 +5,7 @@ import (
 	"assessment/pkg/database/mongodb/models"
 	"context"
 	"fmt"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -78,11 +79,16 @@ func (repo *OrganizationRepo) UpdateOrganization(organizationID string, updateDa
 		return nil, fmt.Errorf("invalid id: %v", err)
 	}
 
+	// Only the fields provided are changed.
 	filter := bson.M{"_id": objectID}
-	update := bson.M{"$set": bson.M{
-		"name":        updateData.Name,
-		"description": updateData.Description,
-	}}
+	fields := bson.M{}
+	if updateData.Name != "" {
+		fields["name"] = updateData.Name
+	}
+	if updateData.Description != "" {
+		fields["description"] = updateData.Description
+	}
+	update := bson.M{"$set": fields}
 
 	// Set the ReturnDocument option to After to get the updated document
 	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
@@ -110,20 +116,233 @@ func (repo *OrganizationRepo) DeleteOrganization(organizationID string) error {
 	return nil
 }
 
-func (repo *OrganizationRepo) InviteUserToOrganization(organizationID, userEmail string) error {
+func (repo *OrganizationRepo) GetOrganizationsByIds(organizationIDs []primitive.ObjectID) ([]*models.Organization, error) {
+	var organizations []*models.Organization
+
+	filter := bson.M{"_id": bson.M{"$in": organizationIDs}}
+	cursor, err := repo.collection.Find(context.Background(), filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	for cursor.Next(context.Background()) {
+		var org models.Organization
+		err := cursor.Decode(&org)
+		if err != nil {
+			return nil, err
+		}
+		organizations = append(organizations, &org)
+	}
+
+	return organizations, nil
+}
+
+func (repo *OrganizationRepo) AddRole(organizationID string, role *models.Role) (bool, error) {
+	objectID, err := primitive.ObjectIDFromHex(organizationID)
+	if err != nil {
+		return false, fmt.Errorf("invalid id: %v", err)
+	}
+
+	// Only add the role when no role with the same name exists yet.
+	filter := bson.M{"_id": objectID, "roles.name": bson.M{"$ne": role.Name}}
+	update := bson.M{"$push": bson.M{"roles": role}}
+
+	result, err := repo.collection.UpdateOne(context.Background(), filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+func (repo *OrganizationRepo) UpdateRole(organizationID, roleName string, permissions []string) (bool, error) {
+	objectID, err := primitive.ObjectIDFromHex(organizationID)
+	if err != nil {
+		return false, fmt.Errorf("invalid id: %v", err)
+	}
+
+	filter := bson.M{"_id": objectID, "roles.name": roleName}
+	update := bson.M{"$set": bson.M{"roles.$.permissions": permissions}}
+
+	result, err := repo.collection.UpdateOne(context.Background(), filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	return result.MatchedCount == 1, nil
+}
+
+func (repo *OrganizationRepo) DeleteRole(organizationID, roleName string) (bool, error) {
+	objectID, err := primitive.ObjectIDFromHex(organizationID)
+	if err != nil {
+		return false, fmt.Errorf("invalid id: %v", err)
+	}
+
+	filter := bson.M{"_id": objectID, "roles.name": roleName}
+	update := bson.M{"$pull": bson.M{"roles": bson.M{"name": roleName}}}
+
+	result, err := repo.collection.UpdateOne(context.Background(), filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+// UpdateSecuritySettings changes the security settings provided and returns the updated organization.
+func (repo *OrganizationRepo) UpdateSecuritySettings(organizationID string, settings *models.SecuritySettingsUpdate) (*models.Organization, error) {
+	var updatedOrganization models.Organization
 
+	objectID, err := primitive.ObjectIDFromHex(organizationID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id: %v", err)
+	}
+
+	fields := bson.M{}
+	if settings.RequireMFA != nil {
+		fields["require_mfa"] = *settings.RequireMFA
+	}
+	if settings.EnforceSSO != nil {
+		fields["enforce_sso"] = *settings.EnforceSSO
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err = repo.collection.FindOneAndUpdate(context.Background(), bson.M{"_id": objectID}, bson.M{"$set": fields}, opts).Decode(&updatedOrganization)
+	if err != nil {
+		return nil, err
+	}
+
+	return &updatedOrganization, nil
+}
+
+// SetSAMLSettings configures the SAML identity provider of an organization.
+func (repo *OrganizationRepo) SetSAMLSettings(organizationID string, settings *models.SAMLSettings) error {
 	objectID, err := primitive.ObjectIDFromHex(organizationID)
 	if err != nil {
 		return fmt.Errorf("invalid id: %v", err)
 	}
 
-	filter := bson.M{"_id": objectID}
-	update := bson.M{"$addToSet": bson.M{"invited_users": userEmail}}
+	update := bson.M{"$set": bson.M{"saml": settings}}
+	_, err = repo.collection.UpdateOne(context.Background(), bson.M{"_id": objectID}, update)
+	return err
+}
+
+// RemoveSAMLSettings removes the SAML identity provider of an organization. Single sign-on can
+// no longer be enforced without one, so members may sign in with their password again.
+func (repo *OrganizationRepo) RemoveSAMLSettings(organizationID string) error {
+	objectID, err := primitive.ObjectIDFromHex(organizationID)
+	if err != nil {
+		return fmt.Errorf("invalid id: %v", err)
+	}
 
+	update := bson.M{"$unset": bson.M{"saml": "", "enforce_sso": ""}}
+	_, err = repo.collection.UpdateOne(context.Background(), bson.M{"_id": objectID}, update)
+	return err
+}
+
+// AddDomain claims an email domain for an organization. It reports false when the organization
+// already claims the domain.
+func (repo *OrganizationRepo) AddDomain(organizationID string, domain *models.OrganizationDomain) (bool, error) {
+	objectID, err := primitive.ObjectIDFromHex(organizationID)
+	if err != nil {
+		return false, fmt.Errorf("invalid id: %v", err)
+	}
+
+	filter := bson.M{"_id": objectID, "domains.name": bson.M{"$ne": domain.Name}}
+	update := bson.M{"$push": bson.M{"domains": domain}}
+
+	result, err := repo.collection.UpdateOne(context.Background(), filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+// MarkDomainVerified records that the organization proved the ownership of a domain it claims.
+func (repo *OrganizationRepo) MarkDomainVerified(organizationID, domain string, verifiedAt time.Time) error {
+	objectID, err := primitive.ObjectIDFromHex(organizationID)
+	if err != nil {
+		return fmt.Errorf("invalid id: %v", err)
+	}
+
+	filter := bson.M{"_id": objectID, "domains.name": domain}
+	update := bson.M{"$set": bson.M{"domains.$.verified_at": verifiedAt}}
 	_, err = repo.collection.UpdateOne(context.Background(), filter, update)
+	return err
+}
+
+// DeleteDomain withdraws the claim of an organization on a domain.
+func (repo *OrganizationRepo) DeleteDomain(organizationID, domain string) (bool, error) {
+	objectID, err := primitive.ObjectIDFromHex(organizationID)
 	if err != nil {
-		return err
+		return false, fmt.Errorf("invalid id: %v", err)
 	}
 
-	return nil
+	filter := bson.M{"_id": objectID, "domains.name": domain}
+	update := bson.M{"$pull": bson.M{"domains": bson.M{"name": domain}}}
+
+	result, err := repo.collection.UpdateOne(context.Background(), filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+// GetOrganizationByVerifiedDomain retrieves the organization that proved the ownership of a domain.
+// It returns nil without an error when no organization did.
+func (repo *OrganizationRepo) GetOrganizationByVerifiedDomain(domain string) (*models.Organization, error) {
+	var org models.Organization
+
+	filter := bson.M{"domains": bson.M{"$elemMatch": bson.M{"name": domain, "verified_at": bson.M{"$exists": true}}}}
+	err := repo.collection.FindOne(context.Background(), filter).Decode(&org)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &org, nil
+}
+
+// GetOrganizationsWithoutOwner lists the organizations no member owns, which nobody can manage
+// until an administrator assigns an owner.
+func (repo *OrganizationRepo) GetOrganizationsWithoutOwner() ([]*models.Organization, error) {
+	var organizations []*models.Organization
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$lookup", Value: bson.M{
+			"from": "memberships",
+			"let":  bson.M{"organization_id": "$_id"},
+			"pipeline": bson.A{
+				bson.M{"$match": bson.M{"$expr": bson.M{"$and": bson.A{
+					bson.M{"$eq": bson.A{"$organization_id", "$$organization_id"}},
+					bson.M{"$eq": bson.A{"$role", models.RoleOwner}},
+				}}}},
+				bson.M{"$limit": 1},
+			},
+			"as": "owners",
+		}}},
+		{{Key: "$match", Value: bson.M{"owners": bson.M{"$size": 0}}}},
+		{{Key: "$project", Value: bson.M{"owners": 0}}},
+	}
+	cursor, err := repo.collection.Aggregate(context.Background(), pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	for cursor.Next(context.Background()) {
+		var org models.Organization
+		err := cursor.Decode(&org)
+		if err != nil {
+			return nil, err
+		}
+		organizations = append(organizations, &org)
+	}
+
+	return organizations, nil
 }
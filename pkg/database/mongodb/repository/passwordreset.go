@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"assessment/pkg/database"
+	"assessment/pkg/database/mongodb/models"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// PasswordResetRepo represents the MongoDB collection for password reset tokens.
+type PasswordResetRepo struct {
+	collection *mongo.Collection
+}
+
+// NewPasswordResetRepo initializes a new PasswordResetRepo instance.
+func NewPasswordResetRepo() *PasswordResetRepo {
+	db := database.GetDatabase()
+	return &PasswordResetRepo{collection: db.Collection("password_resets")}
+}
+
+// CreatePasswordReset stores a new reset token, discarding the unused tokens the user requested before.
+func (repo *PasswordResetRepo) CreatePasswordReset(reset *models.PasswordReset) error {
+	filter := bson.M{"user_id": reset.UserId, "used_at": bson.M{"$exists": false}}
+	_, err := repo.collection.DeleteMany(context.Background(), filter)
+	if err != nil {
+		return err
+	}
+
+	_, err = repo.collection.InsertOne(context.Background(), reset)
+	return err
+}
+
+// GetPasswordReset retrieves the unused, unexpired reset token with the given hash.
+// It returns nil without an error when there is none.
+func (repo *PasswordResetRepo) GetPasswordReset(tokenHash string) (*models.PasswordReset, error) {
+	filter := bson.M{
+		"token_hash": tokenHash,
+		"used_at":    bson.M{"$exists": false},
+		"expires_at": bson.M{"$gt": time.Now()},
+	}
+
+	var reset models.PasswordReset
+	err := repo.collection.FindOne(context.Background(), filter).Decode(&reset)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &reset, nil
+}
+
+// MarkPasswordResetUsed consumes a reset token. It reports false when the token was
+// already used, so every token works only once.
+func (repo *PasswordResetRepo) MarkPasswordResetUsed(resetID primitive.ObjectID) (bool, error) {
+	filter := bson.M{"_id": resetID, "used_at": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"used_at": time.Now()}}
+
+	result, err := repo.collection.UpdateOne(context.Background(), filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount == 1, nil
+}
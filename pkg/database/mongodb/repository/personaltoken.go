@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"assessment/pkg/database"
+	"assessment/pkg/database/mongodb/models"
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PersonalTokenRepo represents the MongoDB collection for personal access tokens.
+type PersonalTokenRepo struct {
+	collection *mongo.Collection
+}
+
+// NewPersonalTokenRepo initializes a new PersonalTokenRepo instance.
+func NewPersonalTokenRepo() *PersonalTokenRepo {
+	db := database.GetDatabase()
+	return &PersonalTokenRepo{collection: db.Collection("personal_access_tokens")}
+}
+
+// EnsureIndexes creates the unique index used to look tokens up by hash.
+func (repo *PersonalTokenRepo) EnsureIndexes() error {
+	_, err := repo.collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.M{"token_hash": 1},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// CreateToken inserts a new personal access token and returns its ID.
+func (repo *PersonalTokenRepo) CreateToken(token *models.PersonalAccessToken) (string, error) {
+	result, err := repo.collection.InsertOne(context.Background(), token)
+	if err != nil {
+		return "", err
+	}
+
+	tokenID := result.InsertedID.(primitive.ObjectID)
+	token.Id = tokenID
+	return tokenID.Hex(), nil
+}
+
+// GetTokenByHash retrieves an unexpired personal access token by the hash of its secret.
+// It returns nil without an error when no token matches.
+func (repo *PersonalTokenRepo) GetTokenByHash(tokenHash string) (*models.PersonalAccessToken, error) {
+	filter := bson.M{
+		"token_hash": tokenHash,
+		"$or": []bson.M{
+			{"expires_at": bson.M{"$exists": false}},
+			{"expires_at": bson.M{"$gt": time.Now()}},
+		},
+	}
+
+	var token models.PersonalAccessToken
+	err := repo.collection.FindOne(context.Background(), filter).Decode(&token)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// GetToken retrieves a personal access token of a user by its ID.
+// It returns nil without an error when the user has no such token.
+func (repo *PersonalTokenRepo) GetToken(userID, tokenID string) (*models.PersonalAccessToken, error) {
+	filter, err := userTokenFilter(userID, tokenID)
+	if err != nil {
+		return nil, err
+	}
+
+	var token models.PersonalAccessToken
+	err = repo.collection.FindOne(context.Background(), filter).Decode(&token)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// GetTokensByUser lists the personal access tokens of a user, newest first.
+func (repo *PersonalTokenRepo) GetTokensByUser(userID string) ([]*models.PersonalAccessToken, error) {
+	var tokens []*models.PersonalAccessToken
+
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id: %v", err)
+	}
+
+	opts := options.Find().SetSort(bson.M{"created_at": -1})
+	cursor, err := repo.collection.Find(context.Background(), bson.M{"user_id": userObjectID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	for cursor.Next(context.Background()) {
+		var token models.PersonalAccessToken
+		err := cursor.Decode(&token)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, &token)
+	}
+
+	return tokens, nil
+}
+
+// UpdateToken renames a personal access token of a user and replaces its scopes, leaving
+// empty values unchanged. It returns the updated token, or nil when the user has no such token.
+func (repo *PersonalTokenRepo) UpdateToken(userID, tokenID string, updateData *models.PersonalAccessTokenUpdateRequest) (*models.PersonalAccessToken, error) {
+	filter, err := userTokenFilter(userID, tokenID)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := bson.M{}
+	if updateData.Name != "" {
+		fields["name"] = updateData.Name
+	}
+	if updateData.Scopes != nil {
+		fields["scopes"] = updateData.Scopes
+	}
+
+	var token models.PersonalAccessToken
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err = repo.collection.FindOneAndUpdate(context.Background(), filter, bson.M{"$set": fields}, opts).Decode(&token)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// DeleteToken revokes a personal access token of a user. It reports false when the user has no such token.
+func (repo *PersonalTokenRepo) DeleteToken(userID, tokenID string) (bool, error) {
+	filter, err := userTokenFilter(userID, tokenID)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := repo.collection.DeleteOne(context.Background(), filter)
+	if err != nil {
+		return false, err
+	}
+
+	return result.DeletedCount == 1, nil
+}
+
+// DeleteTokensByUser revokes every personal access token of a user.
+func (repo *PersonalTokenRepo) DeleteTokensByUser(userID string) error {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return fmt.Errorf("invalid id: %v", err)
+	}
+
+	_, err = repo.collection.DeleteMany(context.Background(), bson.M{"user_id": userObjectID})
+	return err
+}
+
+// RecordTokenUse stores when and from where a personal access token was last used.
+func (repo *PersonalTokenRepo) RecordTokenUse(id primitive.ObjectID, ip string) error {
+	update := bson.M{"$set": bson.M{"last_used_at": time.Now(), "last_used_ip": ip}}
+	_, err := repo.collection.UpdateOne(context.Background(), bson.M{"_id": id}, update)
+	return err
+}
+
+// userTokenFilter matches a token by ID, only when it belongs to the user.
+func userTokenFilter(userID, tokenID string) (bson.M, error) {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id: %v", err)
+	}
+	tokenObjectID, err := primitive.ObjectIDFromHex(tokenID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id: %v", err)
+	}
+
+	return bson.M{"_id": tokenObjectID, "user_id": userObjectID}, nil
+}
@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"assessment/pkg/database"
+	"assessment/pkg/database/mongodb/models"
+	"context"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// SecurityEventRepo represents the MongoDB collection for the security audit log.
+type SecurityEventRepo struct {
+	collection *mongo.Collection
+}
+
+// NewSecurityEventRepo initializes a new SecurityEventRepo instance.
+func NewSecurityEventRepo() *SecurityEventRepo {
+	db := database.GetDatabase()
+	return &SecurityEventRepo{collection: db.Collection("security_events")}
+}
+
+// CreateEvent appends an event to the audit log.
+func (repo *SecurityEventRepo) CreateEvent(event *models.SecurityEvent) error {
+	_, err := repo.collection.InsertOne(context.Background(), event)
+	return err
+}
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"assessment/pkg/database"
+	"assessment/pkg/database/mongodb/models"
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SessionRepo represents the MongoDB collection for sign-in sessions.
+type SessionRepo struct {
+	collection *mongo.Collection
+}
+
+// NewSessionRepo initializes a new SessionRepo instance.
+func NewSessionRepo() *SessionRepo {
+	db := database.GetDatabase()
+	return &SessionRepo{collection: db.Collection("sessions")}
+}
+
+// CreateSession inserts a new session and returns its ID.
+func (repo *SessionRepo) CreateSession(session *models.Session) (string, error) {
+	result, err := repo.collection.InsertOne(context.Background(), session)
+	if err != nil {
+		return "", err
+	}
+
+	sessionID := result.InsertedID.(primitive.ObjectID).Hex()
+	return sessionID, nil
+}
+
+// GetSessionById retrieves a session by its ID.
+// It returns nil without an error when no session matches.
+func (repo *SessionRepo) GetSessionById(sessionID string) (*models.Session, error) {
+	objectID, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id: %v", err)
+	}
+
+	var session models.Session
+	err = repo.collection.FindOne(context.Background(), bson.M{"_id": objectID}).Decode(&session)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// GetActiveSessionsByUser lists the sessions of a user that have not been revoked, most recently used first.
+func (repo *SessionRepo) GetActiveSessionsByUser(userID string) ([]*models.Session, error) {
+	var sessions []*models.Session
+
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id: %v", err)
+	}
+
+	filter := bson.M{"user_id": userObjectID, "revoked_at": bson.M{"$exists": false}}
+	opts := options.Find().SetSort(bson.M{"last_used_at": -1})
+	cursor, err := repo.collection.Find(context.Background(), filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	for cursor.Next(context.Background()) {
+		var session models.Session
+		err := cursor.Decode(&session)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, &session)
+	}
+
+	return sessions, nil
+}
+
+// TouchSession records that a session has just been used.
+func (repo *SessionRepo) TouchSession(sessionID string) error {
+	objectID, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return fmt.Errorf("invalid id: %v", err)
+	}
+
+	update := bson.M{"$set": bson.M{"last_used_at": time.Now()}}
+	_, err = repo.collection.UpdateOne(context.Background(), bson.M{"_id": objectID}, update)
+	return err
+}
+
+// RevokeSession marks a session as revoked.
+func (repo *SessionRepo) RevokeSession(sessionID string) error {
+	objectID, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return fmt.Errorf("invalid id: %v", err)
+	}
+
+	filter := bson.M{"_id": objectID, "revoked_at": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"revoked_at": time.Now()}}
+	_, err = repo.collection.UpdateOne(context.Background(), filter, update)
+	return err
+}
+
+// RevokeUserSessions marks every session of a user as revoked, except the one with exceptSessionID if given.
+func (repo *SessionRepo) RevokeUserSessions(userID, exceptSessionID string) error {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return fmt.Errorf("invalid id: %v", err)
+	}
+
+	filter := bson.M{"user_id": userObjectID, "revoked_at": bson.M{"$exists": false}}
+	if exceptSessionID != "" {
+		exceptObjectID, err := primitive.ObjectIDFromHex(exceptSessionID)
+		if err != nil {
+			return fmt.Errorf("invalid id: %v", err)
+		}
+		filter["_id"] = bson.M{"$ne": exceptObjectID}
+	}
+
+	update := bson.M{"$set": bson.M{"revoked_at": time.Now()}}
+	_, err = repo.collection.UpdateMany(context.Background(), filter, update)
+	return err
+}
+
+// RevokeClientSessions marks every session created for an OAuth client as revoked.
+func (repo *SessionRepo) RevokeClientSessions(clientID string) error {
+	filter := bson.M{"client_id": clientID, "revoked_at": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"revoked_at": time.Now()}}
+	_, err := repo.collection.UpdateMany(context.Background(), filter, update)
+	return err
+}
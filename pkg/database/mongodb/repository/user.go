@@ -3,11 +3,17 @@ package repository
 import (
 	"assessment/pkg/database"
 	"assessment/pkg/database/mongodb/models"
+	"assessment/pkg/utils"
 	"context"
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // UserRepo represents the MongoDB collection for user data.
@@ -21,17 +27,66 @@ func NewUserRepo() *UserRepo {
 	return &UserRepo{collection: db.Collection("user")}
 }
 
-// CreateUser inserts a new user into the database.
+// ErrEmailExists is returned when another account already uses the normalized email address.
+var ErrEmailExists = errors.New("email already exists")
+
+// EnsureIndexes fills in the normalized email of users created before it existed and creates
+// the unique index on it. Accounts whose addresses only differ in case have to be merged by
+// hand before the index can be built.
+func (repo *UserRepo) EnsureIndexes() error {
+	cursor, err := repo.collection.Find(context.Background(), bson.M{"normalized_email": bson.M{"$exists": false}})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(context.Background())
+
+	for cursor.Next(context.Background()) {
+		var user models.User
+		if err := cursor.Decode(&user); err != nil {
+			return err
+		}
+		normalized, err := utils.NormalizeEmail(user.Email)
+		if err != nil {
+			normalized = strings.ToLower(strings.TrimSpace(user.Email))
+		}
+		_, err = repo.collection.UpdateOne(context.Background(), bson.M{"_id": user.Id}, bson.M{"$set": bson.M{"normalized_email": normalized}})
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = repo.collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.M{"normalized_email": 1},
+		Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"normalized_email": bson.M{"$exists": true}}),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create unique email index: %v", err)
+	}
+
+	return nil
+}
+
+// CreateUser inserts a new user into the database. It fails with ErrEmailExists when the
+// normalized email address is taken; the unique index settles concurrent signups.
 func (repo *UserRepo) CreateUser(user *models.User) (*models.User, error) {
+	normalized, err := utils.NormalizeEmail(user.Email)
+	if err != nil {
+		return nil, err
+	}
+	user.NormalizedEmail = normalized
+
 	// Check if the user already exists by email.
 	existingUser := &models.User{}
-	err := repo.collection.FindOne(context.TODO(), bson.M{"email": user.Email}).Decode(existingUser)
+	err = repo.collection.FindOne(context.TODO(), bson.M{"normalized_email": normalized}).Decode(existingUser)
 	if err == nil {
-		return nil, errors.New("email already exists")
+		return nil, ErrEmailExists
 	}
 
 	// Insert the new user into the database.
 	createdUser, err := repo.collection.InsertOne(context.Background(), user)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, ErrEmailExists
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -45,12 +100,38 @@ func (repo *UserRepo) CreateUser(user *models.User) (*models.User, error) {
 	return &insertedUser, nil
 }
 
-// FindUserByEmail retrieves a user from the database by their email address.
+// FindUserByEmail retrieves a user from the database by their email address, matching its
+// normalized form. It returns nil without an error for an invalid address.
 func (repo *UserRepo) FindUserByEmail(email string) (*models.User, error) {
+	normalized, err := utils.NormalizeEmail(email)
+	if err != nil {
+		return nil, nil
+	}
+
 	// Search for the user by email.
-	filter := bson.M{"email": email}
+	filter := bson.M{"normalized_email": normalized}
+	var user models.User
+	err = repo.collection.FindOne(context.Background(), filter).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// FindUserById retrieves a user from the database by their ID.
+func (repo *UserRepo) FindUserById(userID string) (*models.User, error) {
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id: %v", err)
+	}
+
+	filter := bson.M{"_id": objectID}
 	var user models.User
-	err := repo.collection.FindOne(context.Background(), filter).Decode(&user)
+	err = repo.collection.FindOne(context.Background(), filter).Decode(&user)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, nil
@@ -60,3 +141,167 @@ func (repo *UserRepo) FindUserByEmail(email string) (*models.User, error) {
 
 	return &user, nil
 }
+
+// FindUsersByIds retrieves the users with the given IDs. Unknown IDs are skipped.
+func (repo *UserRepo) FindUsersByIds(userIDs []primitive.ObjectID) ([]*models.User, error) {
+	var users []*models.User
+
+	cursor, err := repo.collection.Find(context.Background(), bson.M{"_id": bson.M{"$in": userIDs}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	for cursor.Next(context.Background()) {
+		var user models.User
+		err := cursor.Decode(&user)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, &user)
+	}
+
+	return users, nil
+}
+
+// MarkEmailVerified records that the user has verified their email address.
+func (repo *UserRepo) MarkEmailVerified(userID string) error {
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return fmt.Errorf("invalid id: %v", err)
+	}
+
+	filter := bson.M{"_id": objectID, "email_verified_at": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"email_verified_at": time.Now()}}
+	_, err = repo.collection.UpdateOne(context.Background(), filter, update)
+	return err
+}
+
+// UpdatePassword replaces the password hash of a user.
+func (repo *UserRepo) UpdatePassword(userID, passwordHash string) error {
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return fmt.Errorf("invalid id: %v", err)
+	}
+
+	update := bson.M{"$set": bson.M{"password": passwordHash}}
+	_, err = repo.collection.UpdateOne(context.Background(), bson.M{"_id": objectID}, update)
+	return err
+}
+
+// RehashPassword replaces a password hash with an upgraded hash of the same password.
+// It does nothing when the password was changed in the meantime.
+func (repo *UserRepo) RehashPassword(userID, currentHash, newHash string) error {
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return fmt.Errorf("invalid id: %v", err)
+	}
+
+	filter := bson.M{"_id": objectID, "password": currentHash}
+	update := bson.M{"$set": bson.M{"password": newHash}}
+	_, err = repo.collection.UpdateOne(context.Background(), filter, update)
+	return err
+}
+
+// UpdateEmail replaces the email address of a user, marking it as verified.
+// It fails with ErrEmailExists when another account uses the address.
+func (repo *UserRepo) UpdateEmail(userID, email string) error {
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return fmt.Errorf("invalid id: %v", err)
+	}
+	normalized, err := utils.NormalizeEmail(email)
+	if err != nil {
+		return err
+	}
+
+	update := bson.M{"$set": bson.M{"email": email, "normalized_email": normalized, "email_verified_at": time.Now()}}
+	_, err = repo.collection.UpdateOne(context.Background(), bson.M{"_id": objectID}, update)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrEmailExists
+	}
+	return err
+}
+
+// SetPendingTOTPSecret stores a new, not yet confirmed authenticator secret for a user.
+// It does nothing when the user already has two-factor authentication enabled.
+func (repo *UserRepo) SetPendingTOTPSecret(userID, secret string) (bool, error) {
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return false, fmt.Errorf("invalid id: %v", err)
+	}
+
+	filter := bson.M{"_id": objectID, "totp.enabled": bson.M{"$ne": true}}
+	update := bson.M{"$set": bson.M{"totp": models.TOTPSettings{Secret: secret}}}
+	result, err := repo.collection.UpdateOne(context.Background(), filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	return result.MatchedCount == 1, nil
+}
+
+// EnableTOTP confirms the pending authenticator secret of a user and stores their hashed recovery codes.
+func (repo *UserRepo) EnableTOTP(userID string, step int64, recoveryCodeHashes []string) error {
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return fmt.Errorf("invalid id: %v", err)
+	}
+
+	update := bson.M{"$set": bson.M{
+		"totp.enabled":        true,
+		"totp.enabled_at":     time.Now(),
+		"totp.last_used_step": step,
+		"totp.recovery_codes": recoveryCodeHashes,
+	}}
+	_, err = repo.collection.UpdateOne(context.Background(), bson.M{"_id": objectID}, update)
+	return err
+}
+
+// DisableTOTP removes the authenticator enrollment and recovery codes of a user.
+func (repo *UserRepo) DisableTOTP(userID string) error {
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return fmt.Errorf("invalid id: %v", err)
+	}
+
+	update := bson.M{"$unset": bson.M{"totp": ""}}
+	_, err = repo.collection.UpdateOne(context.Background(), bson.M{"_id": objectID}, update)
+	return err
+}
+
+// RecordTOTPStep stores the time step of an accepted code. It reports false when that step or a
+// later one was already used, so a code cannot be replayed.
+func (repo *UserRepo) RecordTOTPStep(userID string, step int64) (bool, error) {
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return false, fmt.Errorf("invalid id: %v", err)
+	}
+
+	filter := bson.M{"_id": objectID, "totp.last_used_step": bson.M{"$lt": step}}
+	update := bson.M{"$set": bson.M{"totp.last_used_step": step}}
+	result, err := repo.collection.UpdateOne(context.Background(), filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+// ConsumeRecoveryCode removes a hashed recovery code from a user. It reports false when the
+// code is unknown or was already used.
+func (repo *UserRepo) ConsumeRecoveryCode(userID, codeHash string) (bool, error) {
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return false, fmt.Errorf("invalid id: %v", err)
+	}
+
+	filter := bson.M{"_id": objectID, "totp.recovery_codes": codeHash}
+	update := bson.M{"$pull": bson.M{"totp.recovery_codes": codeHash}}
+	result, err := repo.collection.UpdateOne(context.Background(), filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount == 1, nil
+}
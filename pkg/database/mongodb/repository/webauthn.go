@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"assessment/pkg/database"
+	"assessment/pkg/database/mongodb/models"
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// WebAuthnRepo represents the MongoDB collection for passkeys.
+type WebAuthnRepo struct {
+	collection *mongo.Collection
+}
+
+// NewWebAuthnRepo initializes a new WebAuthnRepo instance.
+func NewWebAuthnRepo() *WebAuthnRepo {
+	db := database.GetDatabase()
+	return &WebAuthnRepo{collection: db.Collection("webauthn_credentials")}
+}
+
+// CreateCredential inserts a new passkey and returns its ID.
+func (repo *WebAuthnRepo) CreateCredential(credential *models.WebAuthnCredential) (string, error) {
+	result, err := repo.collection.InsertOne(context.Background(), credential)
+	if err != nil {
+		return "", err
+	}
+
+	credentialID := result.InsertedID.(primitive.ObjectID).Hex()
+	return credentialID, nil
+}
+
+// GetCredential retrieves a passkey by the credential ID the authenticator assigned to it.
+// It returns nil without an error when no passkey matches.
+func (repo *WebAuthnRepo) GetCredential(credentialID string) (*models.WebAuthnCredential, error) {
+	var credential models.WebAuthnCredential
+	err := repo.collection.FindOne(context.Background(), bson.M{"credential_id": credentialID}).Decode(&credential)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &credential, nil
+}
+
+// GetCredentialsByUser lists the passkeys of a user.
+func (repo *WebAuthnRepo) GetCredentialsByUser(userID string) ([]*models.WebAuthnCredential, error) {
+	var credentials []*models.WebAuthnCredential
+
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id: %v", err)
+	}
+
+	cursor, err := repo.collection.Find(context.Background(), bson.M{"user_id": userObjectID})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	for cursor.Next(context.Background()) {
+		var credential models.WebAuthnCredential
+		err := cursor.Decode(&credential)
+		if err != nil {
+			return nil, err
+		}
+		credentials = append(credentials, &credential)
+	}
+
+	return credentials, nil
+}
+
+// RecordCredentialUse stores the sign counter reported by the authenticator after a login.
+// It reports false when the stored counter changed concurrently, so the same assertion
+// cannot be used twice.
+func (repo *WebAuthnRepo) RecordCredentialUse(id primitive.ObjectID, previousCount, signCount uint32) (bool, error) {
+	filter := bson.M{"_id": id, "sign_count": previousCount}
+	update := bson.M{"$set": bson.M{"sign_count": signCount, "last_used_at": time.Now()}}
+	result, err := repo.collection.UpdateOne(context.Background(), filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	return result.MatchedCount == 1, nil
+}
@@ -0,0 +1,173 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/hex"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// cborMap is a CBOR map encoded with its entries in order, as authenticators do.
+type cborMap [][2]interface{}
+
+// encodeTestCBOR encodes the subset of CBOR that decodeCBOR supports, for building test input.
+func encodeTestCBOR(value interface{}) []byte {
+	switch v := value.(type) {
+	case int64:
+		if v < 0 {
+			return cborHead(1, uint64(-1-v))
+		}
+		return cborHead(0, uint64(v))
+	case []byte:
+		return append(cborHead(2, uint64(len(v))), v...)
+	case string:
+		return append(cborHead(3, uint64(len(v))), v...)
+	case []interface{}:
+		data := cborHead(4, uint64(len(v)))
+		for _, item := range v {
+			data = append(data, encodeTestCBOR(item)...)
+		}
+		return data
+	case cborMap:
+		data := cborHead(5, uint64(len(v)))
+		for _, entry := range v {
+			data = append(data, encodeTestCBOR(entry[0])...)
+			data = append(data, encodeTestCBOR(entry[1])...)
+		}
+		return data
+	case bool:
+		if v {
+			return []byte{0xf5}
+		}
+		return []byte{0xf4}
+	case nil:
+		return []byte{0xf6}
+	}
+	panic("unsupported CBOR test value")
+}
+
+// cborHead encodes the initial byte and argument of an item with the shortest form.
+func cborHead(major byte, argument uint64) []byte {
+	head := major << 5
+	switch {
+	case argument < 24:
+		return []byte{head | byte(argument)}
+	case argument <= 0xff:
+		return []byte{head | 24, byte(argument)}
+	case argument <= 0xffff:
+		return []byte{head | 25, byte(argument >> 8), byte(argument)}
+	case argument <= 0xffffffff:
+		return []byte{head | 26, byte(argument >> 24), byte(argument >> 16), byte(argument >> 8), byte(argument)}
+	default:
+		data := []byte{head | 27}
+		for shift := 56; shift >= 0; shift -= 8 {
+			data = append(data, byte(argument>>shift))
+		}
+		return data
+	}
+}
+
+func TestDecodeCBOR(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		want     interface{}
+		wantRest string
+	}{
+		{name: "zero", input: "00", want: int64(0)},
+		{name: "small integer", input: "17", want: int64(23)},
+		{name: "one byte integer", input: "1818", want: int64(24)},
+		{name: "two byte integer", input: "1903e8", want: int64(1000)},
+		{name: "four byte integer", input: "1a000f4240", want: int64(1000000)},
+		{name: "eight byte integer", input: "1b000000e8d4a51000", want: int64(1000000000000)},
+		{name: "negative integer", input: "20", want: int64(-1)},
+		{name: "negative one byte integer", input: "3863", want: int64(-100)},
+		{name: "COSE algorithm", input: "390100", want: COSEAlgRS256},
+		{name: "byte string", input: "43010203", want: []byte{1, 2, 3}},
+		{name: "empty byte string", input: "40", want: []byte(nil)},
+		{name: "text string", input: "6449455446", want: "IETF"},
+		{name: "false", input: "f4", want: false},
+		{name: "true", input: "f5", want: true},
+		{name: "null", input: "f6", want: nil},
+		{name: "array", input: "83010203", want: []interface{}{int64(1), int64(2), int64(3)}},
+		{name: "nested array", input: "8201820203", want: []interface{}{int64(1), []interface{}{int64(2), int64(3)}}},
+		{name: "map with integer keys", input: "a201020304", want: map[interface{}]interface{}{int64(1): int64(2), int64(3): int64(4)}},
+		{name: "map with text keys", input: "a26161016162820203", want: map[interface{}]interface{}{"a": int64(1), "b": []interface{}{int64(2), int64(3)}}},
+		{name: "trailing bytes", input: "0001", want: int64(0), wantRest: "01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input, _ := hex.DecodeString(tt.input)
+			got, rest, err := decodeCBOR(input)
+			if err != nil {
+				t.Fatalf("decodeCBOR() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decodeCBOR() = %#v, want %#v", got, tt.want)
+			}
+			if hex.EncodeToString(rest) != tt.wantRest {
+				t.Errorf("rest = %x, want %s", rest, tt.wantRest)
+			}
+		})
+	}
+}
+
+func TestDecodeCBORErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "empty", input: ""},
+		{name: "missing argument", input: "18"},
+		{name: "short argument", input: "19ff"},
+		{name: "truncated byte string", input: "4201"},
+		{name: "truncated text string", input: "62ff"},
+		{name: "truncated array", input: "8301"},
+		{name: "truncated map", input: "a20102"},
+		{name: "indefinite length", input: "9f01ff"},
+		{name: "reserved argument", input: "1c"},
+		{name: "integer overflow", input: "1bffffffffffffffff"},
+		{name: "negative integer overflow", input: "3bffffffffffffffff"},
+		{name: "tag", input: "c001"},
+		{name: "float", input: "f93c00"},
+		{name: "byte string map key", input: "a1410102"},
+		{name: "duplicate map key", input: "a201020103"},
+		{name: "nesting too deep", input: strings.Repeat("81", cborMaxDepth+1) + "00"},
+		{name: "huge array length", input: "9bffffffffffffffff"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input, err := hex.DecodeString(tt.input)
+			if err != nil {
+				t.Fatalf("invalid test input: %v", err)
+			}
+			if got, _, err := decodeCBOR(input); err == nil {
+				t.Errorf("decodeCBOR() = %#v, want an error", got)
+			}
+		})
+	}
+}
+
+func TestEncodeTestCBORRoundTrip(t *testing.T) {
+	value := cborMap{
+		{int64(1), int64(2)},
+		{int64(-1), bytes.Repeat([]byte{0xab}, 300)},
+		{"name", strings.Repeat("x", 70000)},
+	}
+
+	got, rest, err := decodeCBOR(encodeTestCBOR(value))
+	if err != nil || len(rest) != 0 {
+		t.Fatalf("decodeCBOR() error = %v, rest = %x", err, rest)
+	}
+	want := map[interface{}]interface{}{
+		int64(1):  int64(2),
+		int64(-1): bytes.Repeat([]byte{0xab}, 300),
+		"name":    strings.Repeat("x", 70000),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decodeCBOR() did not return the encoded map")
+	}
+}
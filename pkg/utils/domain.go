@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"errors"
+	"net"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// domainVerificationLabel is prepended to a domain to name the TXT record proving its ownership.
+const domainVerificationLabel = "_sso-verification."
+
+// domainVerificationPrefix starts the value of the TXT record proving the ownership of a domain.
+const domainVerificationPrefix = "sso-verification="
+
+// ErrInvalidDomain is returned for a domain name that cannot receive email.
+var ErrInvalidDomain = errors.New("invalid domain")
+
+// NormalizeDomain returns the lowercase ASCII form of a domain name, as found in normalized email addresses.
+func NormalizeDomain(domain string) (string, error) {
+	domain, err := idna.Lookup.ToASCII(strings.TrimSuffix(strings.TrimSpace(domain), "."))
+	if err != nil || !strings.Contains(domain, ".") {
+		return "", ErrInvalidDomain
+	}
+	return strings.ToLower(domain), nil
+}
+
+// EmailDomain returns the domain of a normalized email address.
+func EmailDomain(email string) string {
+	return email[strings.LastIndexByte(email, '@')+1:]
+}
+
+// DomainVerificationRecord returns the name and value of the DNS TXT record that proves the
+// ownership of a domain with a verification token.
+func DomainVerificationRecord(domain, token string) (name, value string) {
+	return domainVerificationLabel + domain, domainVerificationPrefix + token
+}
+
+// VerifyDomainOwnership reports whether the domain publishes the TXT record of the verification token.
+func VerifyDomainOwnership(domain, token string) (bool, error) {
+	name, value := DomainVerificationRecord(domain, token)
+
+	records, err := net.LookupTXT(name)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+
+	for _, record := range records {
+		if strings.TrimSpace(record) == value {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,122 @@
+package utils
+
+import (
+	"assessment/config"
+	"bufio"
+	"errors"
+	"log"
+	"net/mail"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Limits of RFC 5321 on the length of an address and its local part.
+const (
+	maxEmailLength     = 254
+	maxEmailLocalParts = 64
+)
+
+// ErrInvalidEmail is returned for addresses that are not a plain RFC 5322 addr-spec.
+var ErrInvalidEmail = errors.New("Email is invalid")
+
+var (
+	disposableDomains     map[string]bool
+	disposableDomainsOnce sync.Once
+)
+
+// NormalizeEmail returns the canonical form of an email address used to identify accounts:
+// Unicode NFC, the local part lowercased and the domain lowercased in its ASCII (punycode) form.
+// Addresses with a display name, comments or surrounding text are rejected.
+func NormalizeEmail(email string) (string, error) {
+	email = norm.NFC.String(strings.TrimSpace(email))
+
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Name != "" || address.Address != email {
+		return "", ErrInvalidEmail
+	}
+
+	at := strings.LastIndexByte(email, '@')
+	if at <= 0 || at == len(email)-1 || at > maxEmailLocalParts {
+		return "", ErrInvalidEmail
+	}
+	local, domain := email[:at], email[at+1:]
+
+	domain, err = idna.Lookup.ToASCII(strings.TrimSuffix(domain, "."))
+	if err != nil || !strings.Contains(domain, ".") {
+		return "", ErrInvalidEmail
+	}
+
+	normalized := strings.ToLower(local) + "@" + strings.ToLower(domain)
+	if len(normalized) > maxEmailLength {
+		return "", ErrInvalidEmail
+	}
+
+	return normalized, nil
+}
+
+// EmailsEqual reports whether two addresses identify the same account.
+func EmailsEqual(a, b string) bool {
+	normalizedA, errA := NormalizeEmail(a)
+	normalizedB, errB := NormalizeEmail(b)
+	if errA != nil || errB != nil {
+		return strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b))
+	}
+	return normalizedA == normalizedB
+}
+
+// IsDisposableEmail reports whether a normalized address belongs to a domain, or a subdomain of a
+// domain, listed in the configured disposable domain blocklist.
+func IsDisposableEmail(normalizedEmail string) bool {
+	cfg := config.GetAppConfig().Email
+	if !cfg.BlockDisposableDomains {
+		return false
+	}
+
+	disposableDomainsOnce.Do(func() {
+		disposableDomains = loadDomainList(cfg.DisposableDomainsFile)
+	})
+
+	domain := normalizedEmail[strings.LastIndexByte(normalizedEmail, '@')+1:]
+	for {
+		if disposableDomains[domain] {
+			return true
+		}
+		dot := strings.IndexByte(domain, '.')
+		if dot < 0 {
+			return false
+		}
+		domain = domain[dot+1:]
+	}
+}
+
+// loadDomainList reads a file with one domain per line; empty lines and # comments are skipped.
+func loadDomainList(path string) map[string]bool {
+	domains := map[string]bool{}
+
+	file, err := os.Open(path)
+	if err != nil {
+		log.Printf("Disposable email domain list unavailable: %v", err)
+		return domains
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if domain, err := idna.Lookup.ToASCII(line); err == nil {
+			domains[strings.ToLower(domain)] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		log.Printf("Failed to read disposable email domain list: %v", err)
+	}
+
+	return domains
+}
@@ -0,0 +1,225 @@
+package utils
+
+import (
+	"assessment/config"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+)
+
+// KeyRetentionPeriod is how long a retired key keeps verifying tokens. It matches the
+// longest-lived token we sign, so nothing issued before the rotation is cut short.
+const KeyRetentionPeriod = InviteTokenExpiry
+
+// SigningKey is a private key used to sign tokens, identified by its kid header.
+type SigningKey struct {
+	Kid        string
+	Method     jwt.SigningMethod
+	PrivateKey crypto.Signer
+	Active     bool
+	RetiredAt  time.Time
+}
+
+// KeySet holds the active signing key and the retired keys still accepted for verification.
+type KeySet struct {
+	keys   map[string]*SigningKey
+	active *SigningKey
+}
+
+// JWK is the public part of a signing key in JSON Web Key format.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// LoadKeySet reads the private keys listed in the configuration. Without configured keys it
+// generates an ephemeral key, unless gin runs in release mode.
+func LoadKeySet(cfg config.JWTConfig) (*KeySet, error) {
+	keySet := &KeySet{keys: map[string]*SigningKey{}}
+
+	for _, keyConfig := range cfg.Keys {
+		key, err := loadSigningKey(keyConfig)
+		if err != nil {
+			return nil, fmt.Errorf("signing key %q: %w", keyConfig.Kid, err)
+		}
+		if _, exists := keySet.keys[key.Kid]; exists {
+			return nil, fmt.Errorf("signing key %q: duplicate kid", key.Kid)
+		}
+		if key.Active {
+			if keySet.active != nil {
+				return nil, errors.New("more than one active signing key configured")
+			}
+			keySet.active = key
+		}
+		keySet.keys[key.Kid] = key
+	}
+
+	if len(cfg.Keys) > 0 && keySet.active == nil {
+		return nil, errors.New("no active signing key configured")
+	}
+
+	// Fall back to a throwaway key so the application still runs without configuration during
+	// development. In release mode every restart would sign everyone out, so refuse to start.
+	if keySet.active == nil {
+		if gin.Mode() == gin.ReleaseMode {
+			return nil, errors.New("no signing keys configured; configure jwt.keys before running in release mode")
+		}
+		log.Println("WARNING: no signing keys configured, generating an ephemeral EdDSA key.")
+		log.Println("WARNING: tokens will not survive a restart; configure jwt.keys outside development.")
+		_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		kid, err := randomID()
+		if err != nil {
+			return nil, err
+		}
+		keySet.active = &SigningKey{Kid: kid, Method: jwt.SigningMethodEdDSA, PrivateKey: privateKey, Active: true}
+		keySet.keys[kid] = keySet.active
+	}
+
+	return keySet, nil
+}
+
+// loadSigningKey parses the PEM private key of a configured signing key.
+func loadSigningKey(keyConfig config.SigningKeyConfig) (*SigningKey, error) {
+	if keyConfig.Kid == "" {
+		return nil, errors.New("kid is required")
+	}
+
+	pemBytes, err := os.ReadFile(keyConfig.PrivateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	// Retired keys must say when they were retired, otherwise they would verify tokens forever.
+	key := &SigningKey{Kid: keyConfig.Kid, Active: keyConfig.Active}
+	if !key.Active && keyConfig.RetiredAt == "" {
+		return nil, errors.New("retired_at is required for inactive keys")
+	}
+	if keyConfig.RetiredAt != "" {
+		key.RetiredAt, err = time.Parse(time.RFC3339, keyConfig.RetiredAt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid retired_at: %v", err)
+		}
+	}
+
+	switch keyConfig.Algorithm {
+	case "RS256":
+		privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(pemBytes)
+		if err != nil {
+			return nil, err
+		}
+		key.Method, key.PrivateKey = jwt.SigningMethodRS256, privateKey
+	case "ES256":
+		privateKey, err := jwt.ParseECPrivateKeyFromPEM(pemBytes)
+		if err != nil {
+			return nil, err
+		}
+		if privateKey.Curve != elliptic.P256() {
+			return nil, errors.New("ES256 requires a P-256 key")
+		}
+		key.Method, key.PrivateKey = jwt.SigningMethodES256, privateKey
+	case "EdDSA":
+		privateKey, err := jwt.ParseEdPrivateKeyFromPEM(pemBytes)
+		if err != nil {
+			return nil, err
+		}
+		key.Method, key.PrivateKey = jwt.SigningMethodEdDSA, privateKey.(ed25519.PrivateKey)
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", keyConfig.Algorithm)
+	}
+
+	return key, nil
+}
+
+// Sign signs the claims with the active key and sets the kid header.
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.active.Method, claims)
+	token.Header["kid"] = ks.active.Kid
+
+	return token.SignedString(ks.active.PrivateKey)
+}
+
+// Verify parses a token into claims, checking its signature against the key named by its kid header.
+// Keys retired for longer than KeyRetentionPeriod are no longer accepted.
+func (ks *KeySet) Verify(tokenString string, claims jwt.Claims) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := ks.keys[kid]
+		if !ok || !key.verifies(time.Now()) {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return key.PrivateKey.Public(), nil
+	})
+}
+
+// JWKS returns the public keys currently accepted for verification.
+func (ks *KeySet) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+	now := time.Now()
+
+	for _, key := range ks.keys {
+		if key.verifies(now) {
+			jwks.Keys = append(jwks.Keys, key.jwk())
+		}
+	}
+
+	return jwks
+}
+
+// verifies reports whether the key may still be used to verify tokens at the given time.
+func (key *SigningKey) verifies(now time.Time) bool {
+	return key.Active || now.Before(key.RetiredAt.Add(KeyRetentionPeriod))
+}
+
+// jwk converts the public part of the key to JSON Web Key format.
+func (key *SigningKey) jwk() JWK {
+	jwk := JWK{Kid: key.Kid, Use: "sig", Alg: key.Method.Alg()}
+
+	switch publicKey := key.PrivateKey.Public().(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+	case *ecdsa.PublicKey:
+		jwk.Kty = "EC"
+		jwk.Crv = "P-256"
+		jwk.X = base64.RawURLEncoding.EncodeToString(publicKey.X.FillBytes(make([]byte, 32)))
+		jwk.Y = base64.RawURLEncoding.EncodeToString(publicKey.Y.FillBytes(make([]byte, 32)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+	}
+
+	return jwk
+}
@@ -0,0 +1,140 @@
+package utils
+
+import (
+	"assessment/config"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+)
+
+// writeTestKey writes a new EdDSA private key in PEM format and returns its path.
+func writeTestKey(t *testing.T) string {
+	t.Helper()
+
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadKeySetErrors(t *testing.T) {
+	active := config.SigningKeyConfig{Kid: "active", Algorithm: "EdDSA", PrivateKeyFile: writeTestKey(t), Active: true}
+	retired := config.SigningKeyConfig{Kid: "retired", Algorithm: "EdDSA", PrivateKeyFile: writeTestKey(t), RetiredAt: "2024-01-01T00:00:00Z"}
+
+	withoutRetiredAt := retired
+	withoutRetiredAt.RetiredAt = ""
+	invalidRetiredAt := retired
+	invalidRetiredAt.RetiredAt = "yesterday"
+	secondActive := retired
+	secondActive.Active = true
+	duplicate := retired
+	duplicate.Kid = active.Kid
+	missingKid := active
+	missingKid.Kid = ""
+	unsupported := active
+	unsupported.Algorithm = "HS256"
+
+	tests := []struct {
+		name string
+		keys []config.SigningKeyConfig
+	}{
+		{name: "inactive key without retired_at", keys: []config.SigningKeyConfig{active, withoutRetiredAt}},
+		{name: "invalid retired_at", keys: []config.SigningKeyConfig{active, invalidRetiredAt}},
+		{name: "no active key", keys: []config.SigningKeyConfig{retired}},
+		{name: "two active keys", keys: []config.SigningKeyConfig{active, secondActive}},
+		{name: "duplicate kid", keys: []config.SigningKeyConfig{active, duplicate}},
+		{name: "missing kid", keys: []config.SigningKeyConfig{missingKid}},
+		{name: "unsupported algorithm", keys: []config.SigningKeyConfig{unsupported}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadKeySet(config.JWTConfig{Keys: tt.keys}); err == nil {
+				t.Errorf("LoadKeySet() succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestLoadKeySetEphemeralKey(t *testing.T) {
+	defer gin.SetMode(gin.Mode())
+
+	gin.SetMode(gin.DebugMode)
+	keySet, err := LoadKeySet(config.JWTConfig{})
+	if err != nil {
+		t.Fatalf("LoadKeySet() in debug mode error = %v", err)
+	}
+	if keySet.active == nil {
+		t.Errorf("LoadKeySet() in debug mode did not generate an active key")
+	}
+
+	gin.SetMode(gin.ReleaseMode)
+	if _, err := LoadKeySet(config.JWTConfig{}); err == nil {
+		t.Errorf("LoadKeySet() in release mode succeeded without keys, want an error")
+	}
+}
+
+func TestKeySetRetiredKeys(t *testing.T) {
+	active := config.SigningKeyConfig{Kid: "active", Algorithm: "EdDSA", PrivateKeyFile: writeTestKey(t), Active: true}
+	recent := config.SigningKeyConfig{Kid: "recent", Algorithm: "EdDSA", PrivateKeyFile: writeTestKey(t),
+		RetiredAt: time.Now().Add(-time.Hour).Format(time.RFC3339)}
+	expired := config.SigningKeyConfig{Kid: "expired", Algorithm: "EdDSA", PrivateKeyFile: writeTestKey(t),
+		RetiredAt: time.Now().Add(-KeyRetentionPeriod - time.Hour).Format(time.RFC3339)}
+
+	keySet, err := LoadKeySet(config.JWTConfig{Keys: []config.SigningKeyConfig{active, recent, expired}})
+	if err != nil {
+		t.Fatalf("LoadKeySet() error = %v", err)
+	}
+
+	tests := []struct {
+		kid      string
+		verifies bool
+	}{
+		{kid: "active", verifies: true},
+		{kid: "recent", verifies: true},
+		{kid: "expired", verifies: false},
+	}
+
+	published := map[string]bool{}
+	for _, jwk := range keySet.JWKS().Keys {
+		published[jwk.Kid] = true
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.kid, func(t *testing.T) {
+			key := keySet.keys[tt.kid]
+			token := jwt.NewWithClaims(key.Method, &jwt.StandardClaims{Subject: "user"})
+			token.Header["kid"] = key.Kid
+			tokenString, err := token.SignedString(key.PrivateKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = keySet.Verify(tokenString, &jwt.StandardClaims{})
+			if (err == nil) != tt.verifies {
+				t.Errorf("Verify() error = %v, want verified %v", err, tt.verifies)
+			}
+			if published[tt.kid] != tt.verifies {
+				t.Errorf("JWKS() published %q = %v, want %v", tt.kid, published[tt.kid], tt.verifies)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package utils
+
+import (
+	"assessment/config"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// Sign-in throttling. Failed attempts are counted per email and per client IP within
+// LoginFailureWindow. Past the backoff threshold every further failure blocks the next attempt
+// for an exponentially growing delay; LockoutThreshold failures on one email lock the account
+// for LockoutDuration.
+const (
+	LoginFailureWindow      = time.Minute * 15
+	LoginBackoffThreshold   = 3
+	LoginMaxBackoff         = time.Minute * 5
+	LockoutThreshold        = 10
+	LockoutDuration         = time.Minute * 15
+	IPLoginBackoffThreshold = 20
+)
+
+// Redis keys used for sign-in throttling:
+//
+//	login_failures:<scope>:<value> -> failed attempts within the window
+//	login_block:<scope>:<value>    -> "backoff" or "locked" while attempts are refused
+const (
+	loginFailuresKeyPrefix = "login_failures:"
+	loginBlockKeyPrefix    = "login_block:"
+
+	loginBlockBackoff = "backoff"
+	loginBlockLocked  = "locked"
+)
+
+// LoginBlock describes why sign-in attempts are currently refused and for how long.
+type LoginBlock struct {
+	// Locked is set when the account is locked out rather than temporarily backed off.
+	Locked     bool
+	RetryAfter time.Duration
+}
+
+// CheckLoginAllowed returns the block that applies to a sign-in attempt for the email from
+// the IP, or nil when the attempt may proceed.
+func CheckLoginAllowed(email, ip string) (*LoginBlock, error) {
+	redisClient := config.Init_redis()
+
+	for _, key := range []string{loginBlockKeyPrefix + emailScope(email), loginBlockKeyPrefix + ipScope(ip)} {
+		value, err := redisClient.Get(key).Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		retryAfter, err := redisClient.TTL(key).Result()
+		if err != nil {
+			return nil, err
+		}
+		if retryAfter <= 0 {
+			continue
+		}
+		return &LoginBlock{Locked: value == loginBlockLocked, RetryAfter: retryAfter}, nil
+	}
+
+	return nil, nil
+}
+
+// RecordLoginFailure counts a failed sign-in for the email and the IP and applies backoff or
+// lockout. It reports true when this failure locked the account.
+func RecordLoginFailure(email, ip string) (bool, error) {
+	emailFailures, err := countLoginFailure(emailScope(email))
+	if err != nil {
+		return false, err
+	}
+	ipFailures, err := countLoginFailure(ipScope(ip))
+	if err != nil {
+		return false, err
+	}
+
+	if emailFailures >= LockoutThreshold {
+		return true, blockLogin(emailScope(email), loginBlockLocked, LockoutDuration)
+	}
+	if emailFailures >= LoginBackoffThreshold {
+		err = blockLogin(emailScope(email), loginBlockBackoff, loginBackoff(emailFailures-LoginBackoffThreshold))
+		if err != nil {
+			return false, err
+		}
+	}
+	if ipFailures >= IPLoginBackoffThreshold {
+		err = blockLogin(ipScope(ip), loginBlockBackoff, loginBackoff(ipFailures-IPLoginBackoffThreshold))
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return false, nil
+}
+
+// ResetLoginFailures clears the failed attempts of an email after a successful sign-in.
+func ResetLoginFailures(email string) error {
+	return config.Init_redis().Del(loginFailuresKeyPrefix+emailScope(email), loginBlockKeyPrefix+emailScope(email)).Err()
+}
+
+// UnlockAccount lifts the lockout and backoff of an email. It reports whether the account was locked.
+func UnlockAccount(email string) (bool, error) {
+	redisClient := config.Init_redis()
+
+	value, err := redisClient.Get(loginBlockKeyPrefix + emailScope(email)).Result()
+	if err != nil && err != redis.Nil {
+		return false, err
+	}
+
+	err = ResetLoginFailures(email)
+	if err != nil {
+		return false, err
+	}
+
+	return value == loginBlockLocked, nil
+}
+
+// countLoginFailure increments the failure counter of a scope, starting its window on the first failure.
+func countLoginFailure(scope string) (int64, error) {
+	redisClient := config.Init_redis()
+	key := loginFailuresKeyPrefix + scope
+
+	count, err := redisClient.Incr(key).Result()
+	if err != nil {
+		return 0, err
+	}
+	if count == 1 {
+		err = redisClient.Expire(key, LoginFailureWindow).Err()
+	}
+
+	return count, err
+}
+
+// blockLogin refuses sign-in attempts of a scope for the duration.
+func blockLogin(scope, reason string, duration time.Duration) error {
+	return config.Init_redis().Set(loginBlockKeyPrefix+scope, reason, duration).Err()
+}
+
+// loginBackoff returns the delay after the given number of failures past a threshold:
+// one second, doubling with every failure up to LoginMaxBackoff.
+func loginBackoff(excess int64) time.Duration {
+	if excess >= 16 {
+		return LoginMaxBackoff
+	}
+	backoff := time.Second << uint(excess)
+	if backoff > LoginMaxBackoff {
+		return LoginMaxBackoff
+	}
+	return backoff
+}
+
+func emailScope(email string) string {
+	if normalized, err := NormalizeEmail(email); err == nil {
+		return "email:" + normalized
+	}
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipScope(ip string) string {
+	return "ip:" + ip
+}
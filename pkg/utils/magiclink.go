@@ -0,0 +1,21 @@
+package utils
+
+import "assessment/config"
+
+// magicLinkKeyPrefix prefixes the Redis keys of issued, unused magic links by token ID.
+const magicLinkKeyPrefix = "magic_link:"
+
+// StoreMagicLink records an issued magic link so that it can be consumed once.
+func StoreMagicLink(tokenID string) error {
+	return config.Init_redis().Set(magicLinkKeyPrefix+tokenID, 1, MagicLinkExpiry).Err()
+}
+
+// ConsumeMagicLink marks a magic link as used. It reports false when the link was already used.
+func ConsumeMagicLink(tokenID string) (bool, error) {
+	deleted, err := config.Init_redis().Del(magicLinkKeyPrefix + tokenID).Result()
+	if err != nil {
+		return false, err
+	}
+
+	return deleted == 1, nil
+}
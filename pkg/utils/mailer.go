@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"assessment/config"
+	"fmt"
+	"log"
+	"net/smtp"
+	"strings"
+	"sync"
+)
+
+// Mailer delivers plain-text emails.
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+// SMTPMailer sends emails through an SMTP server.
+type SMTPMailer struct {
+	cfg config.MailConfig
+}
+
+// LogMailer writes emails to the log instead of sending them, for local development.
+type LogMailer struct{}
+
+var (
+	mailer     Mailer
+	mailerOnce sync.Once
+)
+
+// InitMailer sets up the mailer returned by Mail from the configuration.
+func InitMailer(cfg config.MailConfig) {
+	mailer = NewMailer(cfg)
+}
+
+// NewMailer returns an SMTP mailer, or a LogMailer when no SMTP host is configured.
+func NewMailer(cfg config.MailConfig) Mailer {
+	if cfg.Host == "" {
+		return LogMailer{}
+	}
+	return &SMTPMailer{cfg: cfg}
+}
+
+// Mail returns the mailer used for all outgoing email.
+func Mail() Mailer {
+	mailerOnce.Do(func() {
+		if mailer == nil {
+			mailer = LogMailer{}
+		}
+	})
+	return mailer
+}
+
+// Send delivers the email through the configured SMTP server.
+func (m *SMTPMailer) Send(to, subject, body string) error {
+	var auth smtp.Auth
+	if m.cfg.Username != "" {
+		auth = smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)
+	}
+
+	// Refuse header injection through the recipient or subject.
+	if strings.ContainsAny(to+subject, "\r\n") {
+		return fmt.Errorf("invalid email header")
+	}
+
+	message := "From: " + m.cfg.From + "\r\n" +
+		"To: " + to + "\r\n" +
+		"Subject: " + subject + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" + body
+
+	addr := fmt.Sprintf("%s:%d", m.cfg.Host, m.cfg.Port)
+	return smtp.SendMail(addr, auth, m.cfg.From, []string{to}, []byte(message))
+}
+
+// Send logs the email.
+func (LogMailer) Send(to, subject, body string) error {
+	log.Printf("Email to %s: %s\n%s", to, subject, body)
+	return nil
+}
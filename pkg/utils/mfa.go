@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"assessment/config"
+	"errors"
+)
+
+// MFAChallengeMaxAttempts is how many codes may be tried against one MFA challenge token.
+const MFAChallengeMaxAttempts = 5
+
+// Redis keys used for MFA challenges:
+//
+//	mfa_attempts:<jti>  -> number of codes tried with the challenge
+//	mfa_completed:<jti> -> set once the challenge has been exchanged for tokens
+const (
+	mfaAttemptsKeyPrefix  = "mfa_attempts:"
+	mfaCompletedKeyPrefix = "mfa_completed:"
+)
+
+var (
+	// ErrMFAChallengeExhausted is returned when too many codes were tried with one challenge.
+	ErrMFAChallengeExhausted = errors.New("too many attempts for this MFA challenge")
+	// ErrMFAChallengeCompleted is returned when a challenge that was already exchanged is presented again.
+	ErrMFAChallengeCompleted = errors.New("MFA challenge already completed")
+)
+
+// RecordMFAAttempt counts a code tried with an MFA challenge and fails once the challenge
+// has been completed or the attempts are used up.
+func RecordMFAAttempt(challengeID string) error {
+	redisClient := config.Init_redis()
+
+	completed, err := redisClient.Exists(mfaCompletedKeyPrefix + challengeID).Result()
+	if err != nil {
+		return err
+	}
+	if completed > 0 {
+		return ErrMFAChallengeCompleted
+	}
+
+	attempts, err := redisClient.Incr(mfaAttemptsKeyPrefix + challengeID).Result()
+	if err != nil {
+		return err
+	}
+	if attempts == 1 {
+		redisClient.Expire(mfaAttemptsKeyPrefix+challengeID, MFAChallengeExpiry)
+	}
+	if attempts > MFAChallengeMaxAttempts {
+		return ErrMFAChallengeExhausted
+	}
+
+	return nil
+}
+
+// CompleteMFAChallenge marks an MFA challenge as used so it cannot be exchanged twice.
+func CompleteMFAChallenge(challengeID string) error {
+	completed, err := config.Init_redis().SetNX(mfaCompletedKeyPrefix+challengeID, 1, MFAChallengeExpiry).Result()
+	if err != nil {
+		return err
+	}
+	if !completed {
+		return ErrMFAChallengeCompleted
+	}
+
+	return nil
+}
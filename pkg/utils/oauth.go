@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"assessment/config"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/go-redis/redis"
+)
+
+// authorizationCodeKeyPrefix prefixes the Redis keys of unused authorization codes by code hash.
+const authorizationCodeKeyPrefix = "oauth_code:"
+
+// AuthorizationCode is what a user approved at the authorization endpoint, waiting to be
+// exchanged for tokens by the client it was issued to.
+type AuthorizationCode struct {
+	ClientId      string   `json:"client_id"`
+	UserId        string   `json:"user_id"`
+	RedirectURI   string   `json:"redirect_uri"`
+	Scopes        []string `json:"scopes"`
+	CodeChallenge string   `json:"code_challenge"`
+	// AuthMethods are those of the session in which the user gave consent.
+	AuthMethods []string `json:"auth_methods"`
+	// Nonce and AuthTime end up in the ID token when the openid scope was granted.
+	Nonce    string `json:"nonce,omitempty"`
+	AuthTime int64  `json:"auth_time,omitempty"`
+}
+
+// StoreAuthorizationCode records an issued authorization code so that it can be exchanged once.
+// Only the hash of the code is used as key.
+func StoreAuthorizationCode(code string, grant *AuthorizationCode) error {
+	value, err := json.Marshal(grant)
+	if err != nil {
+		return err
+	}
+
+	return config.Init_redis().Set(authorizationCodeKeyPrefix+HashToken(code), value, AuthorizationCodeExpiry).Err()
+}
+
+// ConsumeAuthorizationCode returns the grant of an authorization code and deletes it, so a code
+// works only once. It returns nil without an error when the code is unknown, expired or used.
+func ConsumeAuthorizationCode(code string) (*AuthorizationCode, error) {
+	value, err := getAndDelete(authorizationCodeKeyPrefix + HashToken(code))
+	if err != nil || value == "" {
+		return nil, err
+	}
+
+	var grant AuthorizationCode
+	if err := json.Unmarshal([]byte(value), &grant); err != nil {
+		return nil, err
+	}
+	return &grant, nil
+}
+
+// getAndDelete returns the value of a Redis key and deletes it in one transaction, so that
+// only one caller gets the value. It returns an empty string when the key does not exist.
+func getAndDelete(key string) (string, error) {
+	var get *redis.StringCmd
+	_, err := config.Init_redis().TxPipelined(func(pipe redis.Pipeliner) error {
+		get = pipe.Get(key)
+		pipe.Del(key)
+		return nil
+	})
+	if err == redis.Nil {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return get.Val(), nil
+}
+
+// VerifyPKCE checks a code verifier against the S256 code challenge sent with the authorization request.
+func VerifyPKCE(verifier, challenge string) bool {
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
+
+// ParseScope splits a space-separated OAuth scope parameter. The result is never nil,
+// so an empty scope grants nothing rather than everything.
+func ParseScope(scope string) []string {
+	scopes := []string{}
+	seen := map[string]bool{}
+	for _, s := range strings.Fields(scope) {
+		if !seen[s] {
+			seen[s] = true
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes
+}
+
+// FormatScope joins scopes into an OAuth scope parameter.
+func FormatScope(scopes []string) string {
+	return strings.Join(scopes, " ")
+}
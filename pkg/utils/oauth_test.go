@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestVerifyPKCE(t *testing.T) {
+	const (
+		verifier  = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWjOEjXk"
+		challenge = "VYFANLqdx_HDV6BqEhluZJ63rtrIPROSSFdB3P6G83I"
+	)
+
+	tests := []struct {
+		name      string
+		verifier  string
+		challenge string
+		want      bool
+	}{
+		{name: "matching verifier", verifier: verifier, challenge: challenge, want: true},
+		{name: "other verifier", verifier: strings.Replace(verifier, "d", "e", 1), challenge: challenge},
+		{name: "plain challenge", verifier: verifier, challenge: verifier},
+		{name: "padded challenge", verifier: verifier, challenge: challenge + "="},
+		{name: "standard base64 challenge", verifier: verifier, challenge: strings.NewReplacer("-", "+", "_", "/").Replace(challenge)},
+		{name: "empty challenge", verifier: verifier, challenge: ""},
+		{name: "empty verifier", verifier: "", challenge: challenge},
+		{name: "verifier too short", verifier: verifier[:42], challenge: challenge},
+		{name: "shortest verifier", verifier: strings.Repeat("a", 43), challenge: "ZtNPunH49FD35FWYhT5Tv8I7vRKQJ8uxMaL0_9eHjNA", want: true},
+		{name: "longest verifier", verifier: strings.Repeat("a", 128), challenge: "aDbPE7rEAOkQUHHNavRwhN-srU5eMCyUv-0k4BOvtz4", want: true},
+		{name: "verifier too long", verifier: strings.Repeat("a", 129), challenge: "wSywJKLlVRzKDgj86PHF4xRVXMP-9jKe6ZSj23UhZq4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyPKCE(tt.verifier, tt.challenge); got != tt.want {
+				t.Errorf("VerifyPKCE() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseScope(t *testing.T) {
+	tests := []struct {
+		name  string
+		scope string
+		want  []string
+	}{
+		{name: "empty", scope: "", want: []string{}},
+		{name: "whitespace", scope: "  ", want: []string{}},
+		{name: "single", scope: "openid", want: []string{"openid"}},
+		{name: "several", scope: "openid  email\tprofile", want: []string{"openid", "email", "profile"}},
+		{name: "duplicates", scope: "openid email openid", want: []string{"openid", "email"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseScope(tt.scope); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseScope(%q) = %q, want %q", tt.scope, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,402 @@
+package utils
+
+import (
+	"assessment/config"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// oidcLoginKeyPrefix prefixes the Redis keys of sign-ins waiting for the identity provider, by state.
+const oidcLoginKeyPrefix = "oidc_login:"
+
+// oidcClockSkew is how far the clock of an identity provider may be off from ours.
+const oidcClockSkew = time.Minute
+
+// DefaultOIDCScopes are requested from identity providers that configure no scopes.
+var DefaultOIDCScopes = []string{"openid", "email", "profile"}
+
+// OIDCProvider signs users in with an external OpenID Connect identity provider using the
+// authorization code flow with PKCE. Its endpoints and keys are discovered from the issuer.
+type OIDCProvider struct {
+	Name        string
+	config      config.OIDCProviderConfig
+	redirectURL string
+	httpClient  *http.Client
+
+	mu       sync.Mutex
+	metadata *oidcMetadata
+	keys     map[string]crypto.PublicKey
+}
+
+// oidcMetadata is the part of the provider's discovery document used to sign in.
+type oidcMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// OIDCIdentity is what an identity provider asserted about the user in its ID token.
+type OIDCIdentity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// OIDCLogin is a sign-in waiting for the user to come back from the identity provider.
+type OIDCLogin struct {
+	Provider     string `json:"provider"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+}
+
+var oidcProviders = map[string]*OIDCProvider{}
+
+// NewOIDCProvider creates a provider that sends users back to redirectURL. A nil httpClient
+// uses a client with a short timeout.
+func NewOIDCProvider(cfg config.OIDCProviderConfig, redirectURL string, httpClient *http.Client) *OIDCProvider {
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = DefaultOIDCScopes
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &OIDCProvider{
+		Name:        cfg.Name,
+		config:      cfg,
+		redirectURL: redirectURL,
+		httpClient:  httpClient,
+	}
+}
+
+// InitOIDCProviders sets up the configured identity providers returned by GetOIDCProvider.
+// Their callbacks are under baseURL.
+func InitOIDCProviders(cfgs []config.OIDCProviderConfig, baseURL string) error {
+	providers := map[string]*OIDCProvider{}
+	for _, cfg := range cfgs {
+		if cfg.Name == "" || cfg.Issuer == "" || cfg.ClientID == "" {
+			return fmt.Errorf("identity provider %q: name, issuer and client_id are required", cfg.Name)
+		}
+		if _, exists := providers[cfg.Name]; exists {
+			return fmt.Errorf("identity provider %q: duplicate name", cfg.Name)
+		}
+		redirectURL := strings.TrimSuffix(baseURL, "/") + "/auth/oidc/" + url.PathEscape(cfg.Name) + "/callback"
+		providers[cfg.Name] = NewOIDCProvider(cfg, redirectURL, nil)
+	}
+
+	oidcProviders = providers
+	return nil
+}
+
+// GetOIDCProvider returns the configured identity provider with the name, or nil.
+func GetOIDCProvider(name string) *OIDCProvider {
+	return oidcProviders[name]
+}
+
+// AuthCodeURL returns the provider URL the user is sent to for signing in.
+func (p *OIDCProvider) AuthCodeURL(state, nonce, codeChallenge string) (string, error) {
+	metadata, err := p.discover()
+	if err != nil {
+		return "", err
+	}
+
+	authURL, err := url.Parse(metadata.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid authorization endpoint: %v", err)
+	}
+	query := authURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", p.config.ClientID)
+	query.Set("redirect_uri", p.redirectURL)
+	query.Set("scope", FormatScope(p.config.Scopes))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", codeChallenge)
+	query.Set("code_challenge_method", "S256")
+	authURL.RawQuery = query.Encode()
+
+	return authURL.String(), nil
+}
+
+// Exchange redeems the authorization code the user came back with and returns the identity
+// from the verified ID token, which must carry the nonce of the sign-in.
+func (p *OIDCProvider) Exchange(code, codeVerifier, nonce string) (*OIDCIdentity, error) {
+	metadata, err := p.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.redirectURL},
+		"code_verifier": {codeVerifier},
+	}
+	request, err := http.NewRequest(http.MethodPost, metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+	request.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := p.doJSON(request, &tokens); err != nil {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+	if tokens.IDToken == "" {
+		return nil, errors.New("token response has no ID token")
+	}
+
+	return p.verifyIDToken(tokens.IDToken, nonce)
+}
+
+// verifyIDToken checks the signature, issuer, audience, expiry and nonce of an ID token.
+func (p *OIDCProvider) verifyIDToken(rawIDToken, nonce string) (*OIDCIdentity, error) {
+	metadata, err := p.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	// Time based claims are checked below, allowing for clock skew.
+	parser := &jwt.Parser{
+		ValidMethods:         []string{"RS256", "ES256", "EdDSA"},
+		SkipClaimsValidation: true,
+	}
+	claims := jwt.MapClaims{}
+	_, err = parser.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.publicKey(kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID token: %v", err)
+	}
+
+	now := time.Now()
+	if !claims.VerifyIssuer(metadata.Issuer, true) {
+		return nil, errors.New("ID token has an unexpected issuer")
+	}
+	if !claims.VerifyAudience(p.config.ClientID, true) {
+		return nil, errors.New("ID token has an unexpected audience")
+	}
+	if !claims.VerifyExpiresAt(now.Add(-oidcClockSkew).Unix(), true) {
+		return nil, errors.New("ID token has expired")
+	}
+	if !claims.VerifyIssuedAt(now.Add(oidcClockSkew).Unix(), false) {
+		return nil, errors.New("ID token is issued in the future")
+	}
+	if tokenNonce, _ := claims["nonce"].(string); nonce == "" || tokenNonce != nonce {
+		return nil, errors.New("ID token nonce does not match")
+	}
+
+	identity := &OIDCIdentity{}
+	identity.Subject, _ = claims["sub"].(string)
+	identity.Email, _ = claims["email"].(string)
+	identity.Name, _ = claims["name"].(string)
+	// Some providers send email_verified as a string.
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		identity.EmailVerified = verified
+	case string:
+		identity.EmailVerified = verified == "true"
+	}
+	if identity.Subject == "" {
+		return nil, errors.New("ID token has no subject")
+	}
+
+	return identity, nil
+}
+
+// discover fetches the provider's discovery document once and checks that it belongs to the configured issuer.
+func (p *OIDCProvider) discover() (*oidcMetadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+
+	issuer := strings.TrimSuffix(p.config.Issuer, "/")
+	request, err := http.NewRequest(http.MethodGet, issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var metadata oidcMetadata
+	if err := p.doJSON(request, &metadata); err != nil {
+		return nil, fmt.Errorf("discovery failed: %w", err)
+	}
+	if strings.TrimSuffix(metadata.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("discovery document is for issuer %q", metadata.Issuer)
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JWKSURI == "" {
+		return nil, errors.New("discovery document is missing endpoints")
+	}
+
+	p.metadata = &metadata
+	return p.metadata, nil
+}
+
+// publicKey returns the provider key with the kid, fetching the provider's keys again when
+// the kid is unknown, as happens after the provider rotated its keys.
+func (p *OIDCProvider) publicKey(kid string) (crypto.PublicKey, error) {
+	metadata, err := p.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+
+	request, err := http.NewRequest(http.MethodGet, metadata.JWKSURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	var jwks JWKS
+	if err := p.doJSON(request, &jwks); err != nil {
+		return nil, fmt.Errorf("fetching keys failed: %w", err)
+	}
+
+	keys := map[string]crypto.PublicKey{}
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		if key, err := jwk.PublicKey(); err == nil {
+			keys[jwk.Kid] = key
+		}
+	}
+	p.keys = keys
+
+	key, ok := p.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+// doJSON sends the request and decodes a successful JSON response into v.
+func (p *OIDCProvider) doJSON(request *http.Request, v interface{}) error {
+	response, err := p.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d: %s", response.StatusCode, body)
+	}
+
+	return json.Unmarshal(body, v)
+}
+
+// PublicKey converts an RSA, P-256 or Ed25519 JSON Web Key to a public key.
+func (jwk JWK) PublicKey() (crypto.PublicKey, error) {
+	decode := base64.RawURLEncoding.DecodeString
+
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decode(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		if jwk.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+		x, err := decode(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("point is not on the curve")
+		}
+		return key, nil
+	case "OKP":
+		if jwk.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+		x, err := decode(jwk.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", jwk.Kty)
+	}
+}
+
+// NewPKCE returns a random PKCE code verifier with its S256 code challenge.
+func NewPKCE() (verifier string, challenge string, err error) {
+	verifier, err = GenerateSecureToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	sum := sha256.Sum256([]byte(verifier))
+	return verifier, base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// StoreOIDCLogin records a sign-in sent to an identity provider so that its callback can be completed once.
+func StoreOIDCLogin(state string, login *OIDCLogin) error {
+	value, err := json.Marshal(login)
+	if err != nil {
+		return err
+	}
+
+	return config.Init_redis().Set(oidcLoginKeyPrefix+HashToken(state), value, OIDCLoginExpiry).Err()
+}
+
+// ConsumeOIDCLogin returns the sign-in with the state and deletes it. It returns nil without
+// an error when the state is unknown, expired or used.
+func ConsumeOIDCLogin(state string) (*OIDCLogin, error) {
+	value, err := getAndDelete(oidcLoginKeyPrefix + HashToken(state))
+	if err != nil || value == "" {
+		return nil, err
+	}
+
+	var login OIDCLogin
+	if err := json.Unmarshal([]byte(value), &login); err != nil {
+		return nil, err
+	}
+	return &login, nil
+}
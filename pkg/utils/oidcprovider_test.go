@@ -0,0 +1,384 @@
+package utils
+
+import (
+	"assessment/config"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	testOIDCClientID     = "test-client"
+	testOIDCClientSecret = "test-secret"
+	testOIDCRedirectURL  = "https://app.example.com/auth/oidc/test/callback"
+	testOIDCNonce        = "test-nonce"
+)
+
+// testIdentityProvider is an OpenID Connect identity provider serving discovery, token and JWKS
+// endpoints. Its token endpoint returns an ID token with the claims set by the test.
+type testIdentityProvider struct {
+	server *httptest.Server
+	// issuer is published in the discovery document; the server URL when empty.
+	issuer string
+
+	kid string
+	key *rsa.PrivateKey
+	// signingKid and signingKey sign the ID tokens; kid and key when empty.
+	signingKid  string
+	signingKey  *rsa.PrivateKey
+	signingNone bool
+
+	claims       jwt.MapClaims
+	tokenStatus  int
+	tokenForm    url.Values
+	tokenAuth    [2]string
+	jwksRequests int
+}
+
+func newTestIdentityProvider(t *testing.T) *testIdentityProvider {
+	t.Helper()
+
+	idp := &testIdentityProvider{kid: "key-1", key: newTestRSAKey(t)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		issuer := idp.issuer
+		if issuer == "" {
+			issuer = idp.server.URL
+		}
+		writeTestJSON(w, map[string]string{
+			"issuer":                 issuer,
+			"authorization_endpoint": idp.server.URL + "/authorize",
+			"token_endpoint":         idp.server.URL + "/token",
+			"jwks_uri":               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		idp.tokenForm = r.PostForm
+		idp.tokenAuth[0], idp.tokenAuth[1], _ = r.BasicAuth()
+		if idp.tokenStatus != 0 {
+			http.Error(w, `{"error":"invalid_grant"}`, idp.tokenStatus)
+			return
+		}
+		writeTestJSON(w, map[string]string{"access_token": "access", "token_type": "Bearer", "id_token": idp.idToken(t)})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		idp.jwksRequests++
+		writeTestJSON(w, JWKS{Keys: []JWK{{
+			Kty: "RSA",
+			Kid: idp.kid,
+			Use: "sig",
+			Alg: "RS256",
+			N:   base64.RawURLEncoding.EncodeToString(idp.key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(idp.key.E)).Bytes()),
+		}}})
+	})
+
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+
+	now := time.Now()
+	idp.claims = jwt.MapClaims{
+		"iss":            idp.server.URL,
+		"aud":            testOIDCClientID,
+		"sub":            "subject-1",
+		"email":          "user@example.com",
+		"email_verified": true,
+		"name":           "Test User",
+		"nonce":          testOIDCNonce,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+	}
+	return idp
+}
+
+// provider returns a provider for the identity provider that reaches it through the test server's client.
+func (idp *testIdentityProvider) provider() *OIDCProvider {
+	return NewOIDCProvider(config.OIDCProviderConfig{
+		Name:         "test",
+		Issuer:       idp.server.URL,
+		ClientID:     testOIDCClientID,
+		ClientSecret: testOIDCClientSecret,
+	}, testOIDCRedirectURL, idp.server.Client())
+}
+
+// idToken signs the configured claims.
+func (idp *testIdentityProvider) idToken(t *testing.T) string {
+	if idp.signingNone {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodNone, idp.claims).SignedString(jwt.UnsafeAllowNoneSignatureType)
+		if err != nil {
+			t.Errorf("sign ID token: %v", err)
+		}
+		return token
+	}
+
+	kid, key := idp.kid, idp.key
+	if idp.signingKey != nil {
+		kid, key = idp.signingKid, idp.signingKey
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, idp.claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Errorf("sign ID token: %v", err)
+	}
+	return signed
+}
+
+func newTestRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	return key
+}
+
+func writeTestJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func TestOIDCProviderExchange(t *testing.T) {
+	otherKey := newTestRSAKey(t)
+
+	tests := []struct {
+		name    string
+		setup   func(idp *testIdentityProvider)
+		nonce   string
+		want    *OIDCIdentity
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			want: &OIDCIdentity{Subject: "subject-1", Email: "user@example.com", EmailVerified: true, Name: "Test User"},
+		},
+		{
+			name:  "email verified as string",
+			setup: func(idp *testIdentityProvider) { idp.claims["email_verified"] = "true" },
+			want:  &OIDCIdentity{Subject: "subject-1", Email: "user@example.com", EmailVerified: true, Name: "Test User"},
+		},
+		{
+			name:  "email not verified",
+			setup: func(idp *testIdentityProvider) { idp.claims["email_verified"] = false },
+			want:  &OIDCIdentity{Subject: "subject-1", Email: "user@example.com", Name: "Test User"},
+		},
+		{
+			name:  "audience list",
+			setup: func(idp *testIdentityProvider) { idp.claims["aud"] = []string{"other-client", testOIDCClientID} },
+			want:  &OIDCIdentity{Subject: "subject-1", Email: "user@example.com", EmailVerified: true, Name: "Test User"},
+		},
+		{
+			name:  "expired within clock skew",
+			setup: func(idp *testIdentityProvider) { idp.claims["exp"] = time.Now().Add(-30 * time.Second).Unix() },
+			want:  &OIDCIdentity{Subject: "subject-1", Email: "user@example.com", EmailVerified: true, Name: "Test User"},
+		},
+		{
+			name:    "other nonce",
+			nonce:   "other-nonce",
+			wantErr: true,
+		},
+		{
+			name:    "missing nonce",
+			setup:   func(idp *testIdentityProvider) { delete(idp.claims, "nonce") },
+			wantErr: true,
+		},
+		{
+			name:    "other audience",
+			setup:   func(idp *testIdentityProvider) { idp.claims["aud"] = "other-client" },
+			wantErr: true,
+		},
+		{
+			name:    "other issuer",
+			setup:   func(idp *testIdentityProvider) { idp.claims["iss"] = "https://evil.example.com" },
+			wantErr: true,
+		},
+		{
+			name:    "expired",
+			setup:   func(idp *testIdentityProvider) { idp.claims["exp"] = time.Now().Add(-2 * time.Minute).Unix() },
+			wantErr: true,
+		},
+		{
+			name:    "missing expiry",
+			setup:   func(idp *testIdentityProvider) { delete(idp.claims, "exp") },
+			wantErr: true,
+		},
+		{
+			name:    "issued in the future",
+			setup:   func(idp *testIdentityProvider) { idp.claims["iat"] = time.Now().Add(2 * time.Minute).Unix() },
+			wantErr: true,
+		},
+		{
+			name:    "missing subject",
+			setup:   func(idp *testIdentityProvider) { delete(idp.claims, "sub") },
+			wantErr: true,
+		},
+		{
+			name:    "unknown signing key",
+			setup:   func(idp *testIdentityProvider) { idp.signingKid, idp.signingKey = "key-2", otherKey },
+			wantErr: true,
+		},
+		{
+			name:    "signed by another key with the same kid",
+			setup:   func(idp *testIdentityProvider) { idp.signingKid, idp.signingKey = idp.kid, otherKey },
+			wantErr: true,
+		},
+		{
+			name:    "unsigned",
+			setup:   func(idp *testIdentityProvider) { idp.signingNone = true },
+			wantErr: true,
+		},
+		{
+			name:    "token request refused",
+			setup:   func(idp *testIdentityProvider) { idp.tokenStatus = http.StatusBadRequest },
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := newTestIdentityProvider(t)
+			if tt.setup != nil {
+				tt.setup(idp)
+			}
+			nonce := tt.nonce
+			if nonce == "" {
+				nonce = testOIDCNonce
+			}
+
+			identity, err := idp.provider().Exchange("code-1", "verifier-1", nonce)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Exchange() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if *identity != *tt.want {
+				t.Errorf("Exchange() = %+v, want %+v", *identity, *tt.want)
+			}
+
+			// The code is redeemed with the verifier and the client's credentials.
+			form := idp.tokenForm
+			if form.Get("grant_type") != "authorization_code" || form.Get("code") != "code-1" ||
+				form.Get("code_verifier") != "verifier-1" || form.Get("redirect_uri") != testOIDCRedirectURL {
+				t.Errorf("token request form = %v", form)
+			}
+			if idp.tokenAuth != [2]string{testOIDCClientID, testOIDCClientSecret} {
+				t.Errorf("token request credentials = %v", idp.tokenAuth)
+			}
+		})
+	}
+}
+
+func TestOIDCProviderKeyRotation(t *testing.T) {
+	idp := newTestIdentityProvider(t)
+	provider := idp.provider()
+
+	if _, err := provider.Exchange("code-1", "verifier-1", testOIDCNonce); err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	if _, err := provider.Exchange("code-2", "verifier-2", testOIDCNonce); err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	if idp.jwksRequests != 1 {
+		t.Errorf("keys fetched %d times, want them cached", idp.jwksRequests)
+	}
+
+	// A new kid makes the provider fetch the keys again.
+	idp.kid, idp.key = "key-2", newTestRSAKey(t)
+	if _, err := provider.Exchange("code-3", "verifier-3", testOIDCNonce); err != nil {
+		t.Fatalf("Exchange() after key rotation error = %v", err)
+	}
+	if idp.jwksRequests != 2 {
+		t.Errorf("keys fetched %d times, want 2", idp.jwksRequests)
+	}
+}
+
+func TestOIDCProviderAuthCodeURL(t *testing.T) {
+	idp := newTestIdentityProvider(t)
+
+	authURL, err := idp.provider().AuthCodeURL("state-1", testOIDCNonce, "challenge-1")
+	if err != nil {
+		t.Fatalf("AuthCodeURL() error = %v", err)
+	}
+
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("AuthCodeURL() = %q: %v", authURL, err)
+	}
+	if got := parsed.Scheme + "://" + parsed.Host + parsed.Path; got != idp.server.URL+"/authorize" {
+		t.Errorf("authorization endpoint = %q", got)
+	}
+
+	want := map[string]string{
+		"response_type":         "code",
+		"client_id":             testOIDCClientID,
+		"redirect_uri":          testOIDCRedirectURL,
+		"scope":                 "openid email profile",
+		"state":                 "state-1",
+		"nonce":                 testOIDCNonce,
+		"code_challenge":        "challenge-1",
+		"code_challenge_method": "S256",
+	}
+	query := parsed.Query()
+	for key, value := range want {
+		if query.Get(key) != value {
+			t.Errorf("%s = %q, want %q", key, query.Get(key), value)
+		}
+	}
+}
+
+func TestOIDCProviderDiscovery(t *testing.T) {
+	tests := []struct {
+		name    string
+		issuer  func(serverURL string) string
+		wantErr bool
+	}{
+		{name: "issuer with trailing slash", issuer: func(serverURL string) string { return serverURL + "/" }},
+		{name: "other issuer", issuer: func(string) string { return "https://evil.example.com" }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := newTestIdentityProvider(t)
+			idp.issuer = tt.issuer(idp.server.URL)
+
+			_, err := idp.provider().AuthCodeURL("state-1", testOIDCNonce, "challenge-1")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("AuthCodeURL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewPKCE(t *testing.T) {
+	verifier, challenge, err := NewPKCE()
+	if err != nil {
+		t.Fatalf("NewPKCE() error = %v", err)
+	}
+	if !VerifyPKCE(verifier, challenge) {
+		t.Errorf("challenge %q does not match verifier %q", challenge, verifier)
+	}
+
+	other, _, err := NewPKCE()
+	if err != nil {
+		t.Fatalf("NewPKCE() error = %v", err)
+	}
+	if other == verifier {
+		t.Error("NewPKCE() returned the same verifier twice")
+	}
+}
@@ -0,0 +1,269 @@
+package utils
+
+import (
+	"assessment/config"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Supported password hashing algorithms.
+const (
+	PasswordAlgorithmArgon2id = "argon2id"
+	PasswordAlgorithmBcrypt   = "bcrypt"
+)
+
+// Default argon2id parameters, following the OWASP recommendation of 19 MiB, two passes and one lane.
+const (
+	DefaultArgon2Memory      = 19 * 1024
+	DefaultArgon2Iterations  = 2
+	DefaultArgon2Parallelism = 1
+	argon2SaltLength         = 16
+	argon2KeyLength          = 32
+)
+
+// bcryptMaxPasswordLength is the number of bytes bcrypt uses; longer passwords would be silently truncated.
+const bcryptMaxPasswordLength = 72
+
+var (
+	// ErrPasswordTooLong is returned when bcrypt is asked to hash more than it would use.
+	ErrPasswordTooLong = errors.New("password is too long for bcrypt")
+	// ErrUnknownPasswordHash is returned for a stored hash in an unrecognized format.
+	ErrUnknownPasswordHash = errors.New("unknown password hash format")
+)
+
+// PasswordHasher hashes passwords into self-describing strings and verifies them.
+type PasswordHasher interface {
+	// Hash returns the encoded hash of the password.
+	Hash(password string) (string, error)
+	// Verify reports whether the password matches an encoded hash of this hasher's algorithm.
+	Verify(password, encoded string) (bool, error)
+	// Matches reports whether the encoded hash was made by this hasher's algorithm.
+	Matches(encoded string) bool
+	// NeedsRehash reports whether a hash of this algorithm was made with other parameters.
+	NeedsRehash(encoded string) bool
+}
+
+// Argon2idHasher hashes passwords with argon2id into PHC strings such as
+// $argon2id$v=19$m=19456,t=2,p=1$<salt>$<hash>.
+type Argon2idHasher struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+}
+
+// BcryptHasher hashes passwords with bcrypt at the given cost.
+type BcryptHasher struct {
+	Cost int
+}
+
+// Passwords hashes new passwords with the preferred hasher, verifies hashes of any supported
+// algorithm and enforces the password policy.
+type Passwords struct {
+	preferred PasswordHasher
+	hashers   []PasswordHasher
+	policy    *PasswordPolicy
+}
+
+var (
+	passwords     *Passwords
+	passwordsOnce sync.Once
+)
+
+// NewPasswords creates a password service hashing with the configured algorithm and parameters.
+func NewPasswords(cfg config.PasswordConfig) (*Passwords, error) {
+	argon2id := &Argon2idHasher{Memory: cfg.Argon2.Memory, Iterations: cfg.Argon2.Iterations, Parallelism: cfg.Argon2.Parallelism}
+	if argon2id.Memory == 0 {
+		argon2id.Memory = DefaultArgon2Memory
+	}
+	if argon2id.Iterations == 0 {
+		argon2id.Iterations = DefaultArgon2Iterations
+	}
+	if argon2id.Parallelism == 0 {
+		argon2id.Parallelism = DefaultArgon2Parallelism
+	}
+
+	bcryptHasher := &BcryptHasher{Cost: cfg.BcryptCost}
+	if bcryptHasher.Cost == 0 {
+		bcryptHasher.Cost = bcrypt.DefaultCost
+	}
+	if bcryptHasher.Cost < bcrypt.MinCost || bcryptHasher.Cost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("invalid bcrypt cost %d", bcryptHasher.Cost)
+	}
+
+	service := &Passwords{hashers: []PasswordHasher{argon2id, bcryptHasher}, policy: NewPasswordPolicy(cfg.Policy)}
+	switch cfg.Algorithm {
+	case PasswordAlgorithmArgon2id, "":
+		service.preferred = argon2id
+	case PasswordAlgorithmBcrypt:
+		service.preferred = bcryptHasher
+		// bcrypt only uses the first 72 bytes, so longer passwords would be silently truncated.
+		service.policy.MaxBytes = bcryptMaxPasswordLength
+	default:
+		return nil, fmt.Errorf("unsupported password hashing algorithm %q", cfg.Algorithm)
+	}
+
+	return service, nil
+}
+
+// InitPasswords sets up the password service returned by PasswordService from the configuration.
+func InitPasswords(cfg config.PasswordConfig) error {
+	service, err := NewPasswords(cfg)
+	if err != nil {
+		return err
+	}
+
+	passwords = service
+	return nil
+}
+
+// PasswordService returns the password service, hashing with argon2id defaults unless InitPasswords was called.
+func PasswordService() *Passwords {
+	passwordsOnce.Do(func() {
+		if passwords == nil {
+			passwords, _ = NewPasswords(config.PasswordConfig{})
+		}
+	})
+	return passwords
+}
+
+// Validate checks a password against the password policy, see PasswordPolicy.Validate.
+func (p *Passwords) Validate(password string, personalInfo ...string) error {
+	return p.policy.Validate(password, personalInfo...)
+}
+
+// Hash hashes a password with the preferred algorithm.
+func (p *Passwords) Hash(password string) (string, error) {
+	return p.preferred.Hash(password)
+}
+
+// Verify checks a password against a hash made by any supported algorithm.
+func (p *Passwords) Verify(password, encoded string) (bool, error) {
+	for _, hasher := range p.hashers {
+		if hasher.Matches(encoded) {
+			return hasher.Verify(password, encoded)
+		}
+	}
+	return false, ErrUnknownPasswordHash
+}
+
+// NeedsRehash reports whether a hash was made with another algorithm or other parameters than preferred.
+func (p *Passwords) NeedsRehash(encoded string) bool {
+	return !p.preferred.Matches(encoded) || p.preferred.NeedsRehash(encoded)
+}
+
+// Hash hashes the password with a random salt.
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.Iterations, h.Memory, h.Parallelism, argon2KeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, h.Memory, h.Iterations, h.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify recomputes the hash with the parameters and salt stored in the encoded hash.
+func (h *Argon2idHasher) Verify(password, encoded string) (bool, error) {
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	computed := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(computed, key) == 1, nil
+}
+
+// Matches reports whether the hash is an argon2id PHC string.
+func (h *Argon2idHasher) Matches(encoded string) bool {
+	return strings.HasPrefix(encoded, "$argon2id$")
+}
+
+// NeedsRehash reports whether the hash was made with other parameters.
+func (h *Argon2idHasher) NeedsRehash(encoded string) bool {
+	params, _, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+	return *params != *h || len(key) != argon2KeyLength
+}
+
+// decodeArgon2id parses an argon2id PHC string into its parameters, salt and hash.
+func decodeArgon2id(encoded string) (*Argon2idHasher, []byte, []byte, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, nil, nil, ErrUnknownPasswordHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, nil, nil, fmt.Errorf("unsupported argon2 version %q", parts[2])
+	}
+
+	params := &Argon2idHasher{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid argon2 parameters: %v", err)
+	}
+	if params.Memory == 0 || params.Iterations == 0 || params.Parallelism == 0 {
+		return nil, nil, nil, errors.New("invalid argon2 parameters")
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid argon2 salt: %v", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return nil, nil, nil, errors.New("invalid argon2 hash")
+	}
+
+	return params, salt, key, nil
+}
+
+// Hash hashes the password with bcrypt. Passwords bcrypt would truncate are refused.
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	if len(password) > bcryptMaxPasswordLength {
+		return "", ErrPasswordTooLong
+	}
+
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), h.Cost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(bytes), nil
+}
+
+// Verify compares the password with a bcrypt hash.
+func (h *BcryptHasher) Verify(password, encoded string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if err != nil {
+		if err == bcrypt.ErrMismatchedHashAndPassword {
+			// Password does not match the hash
+			return false, nil
+		}
+		// An unexpected error occurred
+		return false, err
+	}
+	return true, nil
+}
+
+// Matches reports whether the hash is a bcrypt hash.
+func (h *BcryptHasher) Matches(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+// NeedsRehash reports whether the hash was made at another cost.
+func (h *BcryptHasher) NeedsRehash(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost != h.Cost
+}
@@ -0,0 +1,235 @@
+package utils
+
+import (
+	"assessment/config"
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Default password policy limits, used when the configuration leaves them unset.
+const (
+	DefaultPasswordMinLength = 8
+	DefaultPasswordMaxLength = 128
+)
+
+// Rules of the password policy, reported in PasswordViolation.Rule.
+const (
+	PasswordRuleRequired     = "required"
+	PasswordRuleMinLength    = "min_length"
+	PasswordRuleMaxLength    = "max_length"
+	PasswordRuleCharClasses  = "character_classes"
+	PasswordRuleEntropy      = "entropy"
+	PasswordRulePersonalInfo = "personal_info"
+	PasswordRuleBreached     = "breached"
+)
+
+// breachedPrefixLength is the number of hex characters of the SHA-1 hash that name a range file.
+const breachedPrefixLength = 5
+
+// PasswordViolation is a policy rule a password fails.
+type PasswordViolation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// PasswordPolicyError lists every rule a password fails.
+type PasswordPolicyError struct {
+	Violations []PasswordViolation
+}
+
+func (e *PasswordPolicyError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, violation := range e.Violations {
+		messages = append(messages, violation.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// PasswordPolicy decides which passwords users may choose.
+type PasswordPolicy struct {
+	MinLength            int
+	MaxLength            int
+	MinCharacterClasses  int
+	MinEntropyBits       float64
+	DisallowPersonalInfo bool
+	// BreachedPasswordsDir holds range files of breached password hashes: for a 5 character
+	// SHA-1 prefix a file <PREFIX>.txt with a line per hash holding the remaining 35 characters,
+	// optionally followed by :<count> as returned by the Pwned Passwords range API.
+	BreachedPasswordsDir string
+	// MaxBytes limits the encoded length for hashers that truncate, 0 meaning no limit.
+	MaxBytes int
+}
+
+// NewPasswordPolicy creates a password policy from the configuration.
+func NewPasswordPolicy(cfg config.PasswordPolicyConfig) *PasswordPolicy {
+	policy := &PasswordPolicy{
+		MinLength:            cfg.MinLength,
+		MaxLength:            cfg.MaxLength,
+		MinCharacterClasses:  cfg.MinCharacterClasses,
+		MinEntropyBits:       cfg.MinEntropyBits,
+		DisallowPersonalInfo: cfg.DisallowPersonalInfo,
+		BreachedPasswordsDir: cfg.BreachedPasswordsDir,
+	}
+	if policy.MinLength == 0 {
+		policy.MinLength = DefaultPasswordMinLength
+	}
+	if policy.MaxLength == 0 {
+		policy.MaxLength = DefaultPasswordMaxLength
+	}
+
+	if policy.BreachedPasswordsDir != "" {
+		if _, err := os.Stat(policy.BreachedPasswordsDir); err != nil {
+			log.Printf("Breached password list unavailable, passwords will not be checked against it: %v", err)
+			policy.BreachedPasswordsDir = ""
+		}
+	}
+
+	return policy
+}
+
+// Validate checks a password against every rule of the policy and returns a *PasswordPolicyError
+// listing all failures. personalInfo holds values the password must not contain, such as the
+// user's name and email address.
+func (p *PasswordPolicy) Validate(password string, personalInfo ...string) error {
+	if password == "" {
+		return &PasswordPolicyError{Violations: []PasswordViolation{{Rule: PasswordRuleRequired, Message: "Password is required"}}}
+	}
+
+	var violations []PasswordViolation
+	violate := func(rule, format string, args ...interface{}) {
+		violations = append(violations, PasswordViolation{Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		violate(PasswordRuleMinLength, "Password must be at least %d characters long", p.MinLength)
+	}
+	if length > p.MaxLength {
+		violate(PasswordRuleMaxLength, "Password must be at most %d characters long", p.MaxLength)
+	} else if p.MaxBytes > 0 && len(password) > p.MaxBytes {
+		violate(PasswordRuleMaxLength, "Password must be at most %d bytes long", p.MaxBytes)
+	}
+
+	classes, poolSize := characterClasses(password)
+	if classes < p.MinCharacterClasses {
+		violate(PasswordRuleCharClasses, "Password must use at least %d of lowercase letters, uppercase letters, digits and symbols", p.MinCharacterClasses)
+	}
+	if p.MinEntropyBits > 0 && float64(length)*math.Log2(float64(poolSize)) < p.MinEntropyBits {
+		violate(PasswordRuleEntropy, "Password is too easy to guess, use a longer password or more kinds of characters")
+	}
+
+	if p.DisallowPersonalInfo && containsPersonalInfo(password, personalInfo) {
+		violate(PasswordRulePersonalInfo, "Password must not contain your name or email address")
+	}
+
+	breached, err := p.isBreached(password)
+	if err != nil {
+		log.Printf("Failed to check breached password list: %v", err)
+	}
+	if breached {
+		violate(PasswordRuleBreached, "Password has appeared in a data breach, choose a different one")
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// isBreached looks the password up in the range file of its SHA-1 prefix.
+func (p *PasswordPolicy) isBreached(password string) (bool, error) {
+	if p.BreachedPasswordsDir == "" {
+		return false, nil
+	}
+
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:breachedPrefixLength], hash[breachedPrefixLength:]
+
+	file, err := os.Open(filepath.Join(p.BreachedPasswordsDir, prefix+".txt"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			line = line[:i]
+		}
+		if strings.EqualFold(line, suffix) {
+			return true, nil
+		}
+	}
+
+	return false, scanner.Err()
+}
+
+// characterClasses counts the kinds of characters in a password and the size of the
+// alphabet they span, for a rough entropy estimate.
+func characterClasses(password string) (int, int) {
+	var lower, upper, digit, symbol, other bool
+	for _, r := range password {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		case r < unicode.MaxASCII && unicode.IsPrint(r):
+			symbol = true
+		default:
+			other = true
+		}
+	}
+
+	classes, poolSize := 0, 0
+	for _, class := range []struct {
+		present bool
+		size    int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {symbol, 33}, {other, 100}} {
+		if class.present {
+			classes++
+			poolSize += class.size
+		}
+	}
+
+	return classes, poolSize
+}
+
+// containsPersonalInfo reports whether the password contains one of the values, or the local
+// part of an email address among them, ignoring case. Values shorter than three characters are ignored.
+func containsPersonalInfo(password string, personalInfo []string) bool {
+	password = strings.ToLower(password)
+
+	for _, value := range personalInfo {
+		value = strings.ToLower(strings.TrimSpace(value))
+		candidates := []string{value}
+		if at := strings.LastIndexByte(value, '@'); at > 0 {
+			candidates = append(candidates, value[:at])
+		}
+		candidates = append(candidates, strings.Fields(value)...)
+
+		for _, candidate := range candidates {
+			if len(candidate) >= 3 && strings.Contains(password, candidate) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"assessment/config"
+	"time"
+)
+
+// rateLimitKeyPrefix prefixes the Redis counters of fixed-window rate limits.
+const rateLimitKeyPrefix = "rate_limit:"
+
+// AllowRequest counts a request against a fixed-window limit identified by key. It reports
+// whether the request is within the limit and, when it is not, how long until the window resets.
+func AllowRequest(key string, limit int64, window time.Duration) (bool, time.Duration, error) {
+	redisClient := config.Init_redis()
+	key = rateLimitKeyPrefix + key
+
+	count, err := redisClient.Incr(key).Result()
+	if err != nil {
+		return false, 0, err
+	}
+	if count == 1 {
+		err = redisClient.Expire(key, window).Err()
+		if err != nil {
+			return false, 0, err
+		}
+	}
+	if count <= limit {
+		return true, 0, nil
+	}
+
+	retryAfter, err := redisClient.TTL(key).Result()
+	if err != nil {
+		return false, 0, err
+	}
+	if retryAfter < 0 {
+		// The counter lost its expiry; start the window over.
+		redisClient.Expire(key, window)
+		retryAfter = window
+	}
+
+	return false, retryAfter, nil
+}
@@ -0,0 +1,134 @@
+package utils
+
+import (
+	"assessment/config"
+	"errors"
+)
+
+// Redis keys used for refresh token rotation:
+//
+//	refresh_token:<jti>         -> family ID; deleted when the token is used
+//	refresh_family:<family>     -> user ID; deleted when the family is revoked
+//	refresh_families:<user ID>  -> set of the user's family IDs
+const (
+	refreshTokenKeyPrefix    = "refresh_token:"
+	refreshFamilyKeyPrefix   = "refresh_family:"
+	refreshFamiliesKeyPrefix = "refresh_families:"
+)
+
+var (
+	// ErrRefreshTokenReused is returned when an already used refresh token is presented again.
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+	// ErrRefreshTokenRevoked is returned when the family of a refresh token has been revoked.
+	ErrRefreshTokenRevoked = errors.New("refresh token revoked")
+)
+
+// RotateRefreshToken consumes a refresh token and issues a new token pair in the same family.
+// Presenting a token that was already consumed revokes the entire family.
+func RotateRefreshToken(refreshToken string) (accessToken string, newRefreshToken string, err error) {
+	claims, err := Tokens().Parse(refreshToken, TokenTypeRefresh)
+	if err != nil {
+		return "", "", err
+	}
+
+	redisClient := config.Init_redis()
+
+	// A family that no longer exists has been revoked (logout or detected reuse).
+	active, err := redisClient.Exists(refreshFamilyKeyPrefix + claims.Family).Result()
+	if err != nil {
+		return "", "", err
+	}
+	if active == 0 {
+		return "", "", ErrRefreshTokenRevoked
+	}
+
+	// Consume the token. The signature proves we issued it, so a missing entry means it was already used.
+	deleted, err := redisClient.Del(refreshTokenKeyPrefix + claims.Id).Result()
+	if err != nil {
+		return "", "", err
+	}
+	if deleted == 0 {
+		if err := RevokeRefreshFamily(claims.Subject, claims.Family); err != nil {
+			return "", "", err
+		}
+		return "", "", ErrRefreshTokenReused
+	}
+
+	// Tokens of an OAuth client keep the client and the scopes they were granted.
+	if claims.ClientId != "" {
+		return GenerateOAuthTokens(claims.Subject, claims.Email, claims.SessionId, claims.Family, claims.ClientId, ParseScope(claims.Scope))
+	}
+
+	return GenerateTokens(claims.Subject, claims.Email, claims.SessionId, claims.Family)
+}
+
+// RevokeRefreshFamily revokes every refresh token issued in a family.
+func RevokeRefreshFamily(userID, familyID string) error {
+	redisClient := config.Init_redis()
+
+	err := redisClient.Del(refreshFamilyKeyPrefix + familyID).Err()
+	if err != nil {
+		return err
+	}
+
+	return redisClient.SRem(refreshFamiliesKeyPrefix+userID, familyID).Err()
+}
+
+// RevokeUserRefreshTokens revokes every refresh token family of a user, except exceptFamilyID if given.
+func RevokeUserRefreshTokens(userID, exceptFamilyID string) error {
+	redisClient := config.Init_redis()
+
+	families, err := redisClient.SMembers(refreshFamiliesKeyPrefix + userID).Result()
+	if err != nil {
+		return err
+	}
+
+	for _, familyID := range families {
+		if familyID == exceptFamilyID {
+			continue
+		}
+		if err := RevokeRefreshFamily(userID, familyID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// StartRefreshFamily registers a new refresh token family for a user and returns its ID.
+func StartRefreshFamily(userID string) (string, error) {
+	familyID, err := randomID()
+	if err != nil {
+		return "", err
+	}
+
+	redisClient := config.Init_redis()
+
+	err = redisClient.Set(refreshFamilyKeyPrefix+familyID, userID, RefreshTokenExpiry).Err()
+	if err != nil {
+		return "", err
+	}
+	err = redisClient.SAdd(refreshFamiliesKeyPrefix+userID, familyID).Err()
+	if err != nil {
+		return "", err
+	}
+
+	return familyID, nil
+}
+
+// storeRefreshToken records an issued refresh token and keeps its family and the
+// user's family index alive for as long as the token is valid.
+func storeRefreshToken(userID, tokenID, familyID string) error {
+	redisClient := config.Init_redis()
+
+	err := redisClient.Set(refreshTokenKeyPrefix+tokenID, familyID, RefreshTokenExpiry).Err()
+	if err != nil {
+		return err
+	}
+	err = redisClient.Expire(refreshFamilyKeyPrefix+familyID, RefreshTokenExpiry).Err()
+	if err != nil {
+		return err
+	}
+
+	return redisClient.Expire(refreshFamiliesKeyPrefix+userID, RefreshTokenExpiry).Err()
+}
@@ -0,0 +1,174 @@
+package utils
+
+import (
+	"assessment/config"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/crewjam/saml"
+)
+
+// samlRequestKeyPrefix prefixes the Redis keys of SAML authentication requests waiting for a response, by relay state.
+const samlRequestKeyPrefix = "saml_request:"
+
+// Assertion attributes tried for the email address and name of a user when an organization maps none.
+var (
+	SAMLEmailAttributes = []string{
+		"email",
+		"mail",
+		"http://schemas.xmlsoap.org/ws/2005/05/identity/claims/emailaddress",
+		"urn:oid:0.9.2342.19200300.100.1.3",
+	}
+	SAMLNameAttributes = []string{
+		"name",
+		"displayName",
+		"http://schemas.microsoft.com/identity/claims/displayname",
+		"urn:oid:2.16.840.1.113730.3.1.241",
+	}
+)
+
+// SAMLRequest is an authentication request sent to the identity provider of an organization.
+type SAMLRequest struct {
+	OrganizationId string `json:"organization_id"`
+	RequestId      string `json:"request_id"`
+	// UserId is the signed-in user linking their account, if any.
+	UserId string `json:"user_id,omitempty"`
+}
+
+// ParseSAMLMetadata reads the metadata of a SAML identity provider, either an EntityDescriptor
+// or an EntitiesDescriptor holding one identity provider. The identity provider must offer
+// single sign-on with the HTTP-Redirect binding and publish a signing certificate.
+func ParseSAMLMetadata(data []byte) (*saml.EntityDescriptor, error) {
+	var descriptor *saml.EntityDescriptor
+
+	var entity saml.EntityDescriptor
+	if err := xml.Unmarshal(data, &entity); err == nil {
+		descriptor = &entity
+	} else {
+		var entities saml.EntitiesDescriptor
+		if err := xml.Unmarshal(data, &entities); err != nil {
+			return nil, errors.New("metadata is not a SAML EntityDescriptor")
+		}
+		for i := range entities.EntityDescriptors {
+			if len(entities.EntityDescriptors[i].IDPSSODescriptors) > 0 {
+				if descriptor != nil {
+					return nil, errors.New("metadata describes more than one identity provider")
+				}
+				descriptor = &entities.EntityDescriptors[i]
+			}
+		}
+	}
+
+	if descriptor == nil || len(descriptor.IDPSSODescriptors) == 0 {
+		return nil, errors.New("metadata does not describe an identity provider")
+	}
+	if descriptor.EntityID == "" {
+		return nil, errors.New("metadata has no entity ID")
+	}
+
+	sp := saml.ServiceProvider{IDPMetadata: descriptor}
+	if sp.GetSSOBindingLocation(saml.HTTPRedirectBinding) == "" {
+		return nil, errors.New("identity provider does not support the HTTP-Redirect binding")
+	}
+
+	hasCertificate := false
+	for _, idp := range descriptor.IDPSSODescriptors {
+		for _, key := range idp.KeyDescriptors {
+			if (key.Use == "" || key.Use == "signing") && len(key.KeyInfo.X509Data.X509Certificates) > 0 {
+				hasCertificate = true
+			}
+		}
+	}
+	if !hasCertificate {
+		return nil, errors.New("metadata has no signing certificate")
+	}
+
+	return descriptor, nil
+}
+
+// NewSAMLServiceProvider returns the SAML service provider of an organization trusting the identity
+// provider with the metadata, which may be nil when only the service provider metadata is needed.
+// Only responses to authentication requests it sent are accepted.
+func NewSAMLServiceProvider(organizationID string, idpMetadata *saml.EntityDescriptor) *saml.ServiceProvider {
+	base := strings.TrimSuffix(config.GetAppConfig().BaseURL, "/") + "/saml/" + url.PathEscape(organizationID)
+	metadataURL, _ := url.Parse(base + "/metadata")
+	acsURL, _ := url.Parse(base + "/acs")
+
+	return &saml.ServiceProvider{
+		EntityID:          metadataURL.String(),
+		MetadataURL:       *metadataURL,
+		AcsURL:            *acsURL,
+		IDPMetadata:       idpMetadata,
+		AuthnNameIDFormat: saml.UnspecifiedNameIDFormat,
+	}
+}
+
+// SAMLMetadata returns the metadata of a service provider. Responses are only accepted with the
+// HTTP-POST binding.
+func SAMLMetadata(sp *saml.ServiceProvider) ([]byte, error) {
+	metadata := sp.Metadata()
+	for i := range metadata.SPSSODescriptors {
+		var services []saml.IndexedEndpoint
+		for _, service := range metadata.SPSSODescriptors[i].AssertionConsumerServices {
+			if service.Binding == saml.HTTPPostBinding {
+				services = append(services, service)
+			}
+		}
+		metadata.SPSSODescriptors[i].AssertionConsumerServices = services
+	}
+
+	data, err := xml.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+// SAMLAttribute returns the first value of the first assertion attribute with one of the names,
+// matching an attribute's name or friendly name.
+func SAMLAttribute(assertion *saml.Assertion, names ...string) string {
+	for _, name := range names {
+		for _, statement := range assertion.AttributeStatements {
+			for _, attribute := range statement.Attributes {
+				if attribute.Name != name && attribute.FriendlyName != name {
+					continue
+				}
+				for _, value := range attribute.Values {
+					if v := strings.TrimSpace(value.Value); v != "" {
+						return v
+					}
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// StoreSAMLRequest records an authentication request sent to an identity provider under the
+// relay state that comes back with the response, so that the response is accepted once.
+func StoreSAMLRequest(relayState string, request *SAMLRequest) error {
+	value, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	return config.Init_redis().Set(samlRequestKeyPrefix+HashToken(relayState), value, SAMLRequestExpiry).Err()
+}
+
+// ConsumeSAMLRequest returns the authentication request with the relay state and deletes it.
+// It returns nil without an error when the relay state is unknown, expired or used.
+func ConsumeSAMLRequest(relayState string) (*SAMLRequest, error) {
+	value, err := getAndDelete(samlRequestKeyPrefix + HashToken(relayState))
+	if err != nil || value == "" {
+		return nil, err
+	}
+
+	var request SAMLRequest
+	if err := json.Unmarshal([]byte(value), &request); err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
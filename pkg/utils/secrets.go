@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// GenerateSecureToken returns a random 256-bit token encoded as URL-safe base64,
+// for one-time secrets that are sent to users and stored only as a hash.
+func GenerateSecureToken() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+// HashToken returns the SHA-256 hash of a token as hex, the form in which one-time secrets are stored.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomID returns a random 128-bit identifier encoded as hex.
+func randomID() (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(bytes), nil
+}
+
+// PersonalAccessTokenPrefix starts every personal access token, telling them apart from JWTs
+// and making leaked tokens easy to spot.
+const PersonalAccessTokenPrefix = "pat_"
+
+// APIKeyPrefix starts every organization API key.
+const APIKeyPrefix = "oak_"
+
+// opaqueTokenVisibleLength is how much of a token after its prefix is kept in clear to identify it.
+const opaqueTokenVisibleLength = 8
+
+// GeneratePersonalAccessToken returns a new personal access token and the visible prefix stored with its hash.
+func GeneratePersonalAccessToken() (token string, prefix string, err error) {
+	return generateOpaqueToken(PersonalAccessTokenPrefix)
+}
+
+// GenerateAPIKey returns a new organization API key and the visible prefix stored with its hash.
+func GenerateAPIKey() (key string, prefix string, err error) {
+	return generateOpaqueToken(APIKeyPrefix)
+}
+
+// generateOpaqueToken returns a random token starting with typePrefix and the part of it kept in clear.
+func generateOpaqueToken(typePrefix string) (token string, prefix string, err error) {
+	secret, err := GenerateSecureToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	token = typePrefix + secret
+	return token, token[:len(typePrefix)+opaqueTokenVisibleLength], nil
+}
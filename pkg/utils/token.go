@@ -0,0 +1,323 @@
+package utils
+
+import (
+	"assessment/config"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// Token types carried in the typ claim. A token is only accepted where its type is expected,
+// so for example a refresh token cannot be used as a bearer access token.
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+	TokenTypeInvite  = "invite"
+	// TokenTypeEmailVerification tokens carry the address they verify, so they stop working if the email changes.
+	TokenTypeEmailVerification = "email_verification"
+	// TokenTypeEmailChange tokens confirm a new address and are bound to the address being replaced.
+	TokenTypeEmailChange = "email_change"
+	// TokenTypeMFAChallenge tokens prove the first step of a sign-in that still needs a second factor.
+	TokenTypeMFAChallenge = "mfa_challenge"
+	// TokenTypeMagicLink tokens sign a user in from an emailed link on the device that requested it.
+	TokenTypeMagicLink = "magic_link"
+)
+
+// Defaults for the iss and aud claims when the configuration leaves them empty.
+const (
+	DefaultIssuer   = "organization-api"
+	DefaultAudience = "organization-api"
+)
+
+// TokenClaims are the claims of every token issued by this API.
+// The subject is the user ID for access and refresh tokens and the invitation ID for invite tokens.
+// Access tokens from the client credentials grant have the OAuth client ID as subject.
+type TokenClaims struct {
+	Email     string `json:"email,omitempty"`
+	Type      string `json:"typ"`
+	SessionId string `json:"sid,omitempty"`
+	Family    string `json:"fam,omitempty"`
+	// PreviousEmail is the address an email change token replaces.
+	PreviousEmail string `json:"prev_email,omitempty"`
+	// AuthMethods are the methods already used in a sign-in awaiting its second factor.
+	AuthMethods []string `json:"amr,omitempty"`
+	// NonceHash binds a magic link to the device holding the nonce it hashes.
+	NonceHash string `json:"nonce_hash,omitempty"`
+	// ClientId is the OAuth client an access or refresh token was issued to.
+	ClientId string `json:"client_id,omitempty"`
+	// Scope lists the space-separated scopes granted to an OAuth client.
+	Scope string `json:"scope,omitempty"`
+	// OrganizationId is the organization a client credentials token acts for.
+	OrganizationId string `json:"org,omitempty"`
+	jwt.StandardClaims
+}
+
+// TokenService issues and validates the JWTs of this API.
+type TokenService struct {
+	keys     *KeySet
+	issuer   string
+	audience string
+}
+
+var (
+	tokenService     *TokenService
+	tokenServiceOnce sync.Once
+)
+
+// NewTokenService creates a token service signing with the given keys.
+func NewTokenService(keys *KeySet, issuer, audience string) *TokenService {
+	if issuer == "" {
+		issuer = DefaultIssuer
+	}
+	if audience == "" {
+		audience = DefaultAudience
+	}
+
+	return &TokenService{keys: keys, issuer: issuer, audience: audience}
+}
+
+// InitTokens loads the configured signing keys and sets up the token service returned by Tokens.
+// Without configured keys an ephemeral EdDSA key is generated, except in release mode.
+func InitTokens(cfg config.JWTConfig) error {
+	keys, err := LoadKeySet(cfg)
+	if err != nil {
+		return err
+	}
+
+	tokenService = NewTokenService(keys, cfg.Issuer, cfg.Audience)
+	return nil
+}
+
+// Tokens returns the token service used for all token issuance and validation.
+func Tokens() *TokenService {
+	tokenServiceOnce.Do(func() {
+		if tokenService == nil {
+			keys, err := LoadKeySet(config.JWTConfig{})
+			if err != nil {
+				log.Fatalf("Unable to generate signing key: %v", err)
+			}
+			tokenService = NewTokenService(keys, "", "")
+		}
+	})
+	return tokenService
+}
+
+// Issuer returns the value of the iss claim of issued tokens.
+func (s *TokenService) Issuer() string {
+	return s.issuer
+}
+
+// SigningAlgorithm returns the algorithm of the key that signs new tokens.
+func (s *TokenService) SigningAlgorithm() string {
+	return s.keys.active.Method.Alg()
+}
+
+// JWKS returns the public keys that verify the issued tokens.
+func (s *TokenService) JWKS() JWKS {
+	return s.keys.JWKS()
+}
+
+// Issue signs the claims as a token valid for ttl. Issuer, audience, issue time, expiry
+// and, when missing, a random token ID are filled in.
+func (s *TokenService) Issue(claims *TokenClaims, ttl time.Duration) (string, error) {
+	now := time.Now()
+
+	if claims.Id == "" {
+		tokenID, err := randomID()
+		if err != nil {
+			return "", err
+		}
+		claims.Id = tokenID
+	}
+	claims.Issuer = s.issuer
+	claims.Audience = s.audience
+	claims.IssuedAt = now.Unix()
+	claims.ExpiresAt = now.Add(ttl).Unix()
+
+	return s.keys.Sign(claims)
+}
+
+// Parse validates a token of the expected type and returns its claims. Besides the signature
+// and expiry it checks the algorithm, type, issuer and audience.
+func (s *TokenService) Parse(tokenString, tokenType string) (*TokenClaims, error) {
+	claims := &TokenClaims{}
+
+	token, err := s.keys.Verify(tokenString, claims)
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	if claims.Type != tokenType {
+		return nil, fmt.Errorf("unexpected token type %q", claims.Type)
+	}
+	if !claims.VerifyIssuer(s.issuer, true) {
+		return nil, errors.New("unexpected token issuer")
+	}
+	if !claims.VerifyAudience(s.audience, true) {
+		return nil, errors.New("unexpected token audience")
+	}
+	if claims.ExpiresAt == 0 || claims.Subject == "" || claims.Id == "" {
+		return nil, errors.New("invalid claims")
+	}
+
+	return claims, nil
+}
+
+// IssueAccessToken creates an access token for a session of a user.
+func (s *TokenService) IssueAccessToken(userID, email, sessionID string) (string, error) {
+	return s.Issue(&TokenClaims{
+		Email:          email,
+		Type:           TokenTypeAccess,
+		SessionId:      sessionID,
+		StandardClaims: jwt.StandardClaims{Subject: userID},
+	}, AccessTokenExpiry)
+}
+
+// IssueRefreshToken creates a refresh token in a rotation family and returns it with its token ID.
+func (s *TokenService) IssueRefreshToken(userID, email, sessionID, familyID string) (string, string, error) {
+	claims := &TokenClaims{
+		Email:          email,
+		Type:           TokenTypeRefresh,
+		SessionId:      sessionID,
+		Family:         familyID,
+		StandardClaims: jwt.StandardClaims{Subject: userID},
+	}
+
+	refreshToken, err := s.Issue(claims, RefreshTokenExpiry)
+	if err != nil {
+		return "", "", err
+	}
+
+	return refreshToken, claims.Id, nil
+}
+
+// IssueOAuthAccessToken creates an access token through which an OAuth client acts for a user
+// within the granted scopes. The session is the one created when the user authorized the client.
+func (s *TokenService) IssueOAuthAccessToken(userID, email, sessionID, clientID string, scopes []string) (string, error) {
+	return s.Issue(&TokenClaims{
+		Email:          email,
+		Type:           TokenTypeAccess,
+		SessionId:      sessionID,
+		ClientId:       clientID,
+		Scope:          FormatScope(scopes),
+		StandardClaims: jwt.StandardClaims{Subject: userID},
+	}, AccessTokenExpiry)
+}
+
+// IssueOAuthRefreshToken creates a refresh token of an OAuth client in a rotation family
+// and returns it with its token ID.
+func (s *TokenService) IssueOAuthRefreshToken(userID, email, sessionID, familyID, clientID string, scopes []string) (string, string, error) {
+	claims := &TokenClaims{
+		Email:          email,
+		Type:           TokenTypeRefresh,
+		SessionId:      sessionID,
+		Family:         familyID,
+		ClientId:       clientID,
+		Scope:          FormatScope(scopes),
+		StandardClaims: jwt.StandardClaims{Subject: userID},
+	}
+
+	refreshToken, err := s.Issue(claims, RefreshTokenExpiry)
+	if err != nil {
+		return "", "", err
+	}
+
+	return refreshToken, claims.Id, nil
+}
+
+// IssueClientCredentialsToken creates an access token through which an OAuth client acts
+// for its own organization rather than for a user.
+func (s *TokenService) IssueClientCredentialsToken(clientID, organizationID string, scopes []string) (string, error) {
+	return s.Issue(&TokenClaims{
+		Type:           TokenTypeAccess,
+		ClientId:       clientID,
+		Scope:          FormatScope(scopes),
+		OrganizationId: organizationID,
+		StandardClaims: jwt.StandardClaims{Subject: clientID},
+	}, AccessTokenExpiry)
+}
+
+// IssueIDToken signs an OpenID Connect ID token telling the client who the user is. Unlike the
+// other tokens it uses the OpenID Connect issuer and has the client as audience, so it is never
+// accepted as an access token. claims holds the user claims released for the granted scopes.
+func (s *TokenService) IssueIDToken(issuer, userID, clientID string, claims map[string]interface{}) (string, error) {
+	now := time.Now()
+
+	idClaims := jwt.MapClaims{}
+	for name, value := range claims {
+		idClaims[name] = value
+	}
+	idClaims["iss"] = issuer
+	idClaims["sub"] = userID
+	idClaims["aud"] = clientID
+	idClaims["iat"] = now.Unix()
+	idClaims["exp"] = now.Add(IDTokenExpiry).Unix()
+
+	return s.keys.Sign(idClaims)
+}
+
+// IssueEmailVerificationToken creates a token confirming that the user owns the email address.
+func (s *TokenService) IssueEmailVerificationToken(userID, email string) (string, error) {
+	return s.Issue(&TokenClaims{
+		Email:          email,
+		Type:           TokenTypeEmailVerification,
+		StandardClaims: jwt.StandardClaims{Subject: userID},
+	}, EmailVerificationExpiry)
+}
+
+// IssueEmailChangeToken creates a token confirming that the user owns the new email address.
+// The change is applied only while the account still uses previousEmail, and the requesting
+// session is kept signed in when the change is confirmed.
+func (s *TokenService) IssueEmailChangeToken(userID, previousEmail, newEmail, sessionID string) (string, error) {
+	return s.Issue(&TokenClaims{
+		Email:          newEmail,
+		PreviousEmail:  previousEmail,
+		Type:           TokenTypeEmailChange,
+		SessionId:      sessionID,
+		StandardClaims: jwt.StandardClaims{Subject: userID},
+	}, EmailVerificationExpiry)
+}
+
+// IssueMFAChallengeToken creates a short-lived token that is exchanged for real tokens
+// once the user presents their second factor. authMethods records how the first step was passed.
+func (s *TokenService) IssueMFAChallengeToken(userID string, authMethods []string) (string, error) {
+	return s.Issue(&TokenClaims{
+		Type:           TokenTypeMFAChallenge,
+		AuthMethods:    authMethods,
+		StandardClaims: jwt.StandardClaims{Subject: userID},
+	}, MFAChallengeExpiry)
+}
+
+// IssueMagicLinkToken creates a sign-in token for an emailed link and returns it with its token ID.
+// The link only works together with the nonce whose hash is given.
+func (s *TokenService) IssueMagicLinkToken(userID, email, nonceHash string) (string, string, error) {
+	claims := &TokenClaims{
+		Email:          email,
+		Type:           TokenTypeMagicLink,
+		NonceHash:      nonceHash,
+		StandardClaims: jwt.StandardClaims{Subject: userID},
+	}
+
+	token, err := s.Issue(claims, MagicLinkExpiry)
+	if err != nil {
+		return "", "", err
+	}
+
+	return token, claims.Id, nil
+}
+
+// IssueInviteToken creates a token identifying an organization invitation.
+func (s *TokenService) IssueInviteToken(invitationID, email string, expiresAt time.Time) (string, error) {
+	return s.Issue(&TokenClaims{
+		Email:          email,
+		Type:           TokenTypeInvite,
+		StandardClaims: jwt.StandardClaims{Subject: invitationID},
+	}, time.Until(expiresAt))
+}
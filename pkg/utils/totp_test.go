@@ -0,0 +1,131 @@
+package utils
+
+import (
+	"encoding/base32"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 seed "12345678901234567890" of the RFC 6238 test vectors, base32 encoded.
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestValidateTOTP(t *testing.T) {
+	// RFC 6238 appendix B, truncated to the six digits authenticator apps show.
+	vectors := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, vector := range vectors {
+		step, valid := ValidateTOTP(rfc6238Secret, vector.code, time.Unix(vector.unix, 0))
+		if !valid {
+			t.Errorf("ValidateTOTP(%s at %d) = invalid", vector.code, vector.unix)
+			continue
+		}
+		if step != vector.unix/30 {
+			t.Errorf("ValidateTOTP(%s at %d) step = %d, want %d", vector.code, vector.unix, step, vector.unix/30)
+		}
+	}
+
+	// 1111111109 is step 37037036, whose code is 081804.
+	at := time.Unix(1111111109, 0)
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		now      time.Time
+		want     bool
+		wantStep int64
+	}{
+		{name: "current step", secret: rfc6238Secret, code: "081804", now: at, want: true, wantStep: 37037036},
+		{name: "previous step", secret: rfc6238Secret, code: "081804", now: at.Add(TOTPPeriod), want: true, wantStep: 37037036},
+		{name: "next step", secret: rfc6238Secret, code: "081804", now: at.Add(-TOTPPeriod), want: true, wantStep: 37037036},
+		{name: "two steps late", secret: rfc6238Secret, code: "081804", now: at.Add(2 * TOTPPeriod)},
+		{name: "two steps early", secret: rfc6238Secret, code: "081804", now: at.Add(-2 * TOTPPeriod)},
+		{name: "lowercase secret", secret: strings.ToLower(rfc6238Secret), code: "081804", now: at, want: true, wantStep: 37037036},
+		{name: "wrong code", secret: rfc6238Secret, code: "081805", now: at},
+		{name: "eight digits", secret: rfc6238Secret, code: "07081804", now: at},
+		{name: "five digits", secret: rfc6238Secret, code: "81804", now: at},
+		{name: "empty code", secret: rfc6238Secret, code: "", now: at},
+		{name: "invalid secret", secret: "not base32!", code: "081804", now: at},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, valid := ValidateTOTP(tt.secret, tt.code, tt.now)
+			if valid != tt.want {
+				t.Fatalf("ValidateTOTP() valid = %v, want %v", valid, tt.want)
+			}
+			if step != tt.wantStep {
+				t.Errorf("ValidateTOTP() step = %d, want %d", step, tt.wantStep)
+			}
+		})
+	}
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("GenerateTOTPSecret() error = %v", err)
+	}
+
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil || len(key) != 20 {
+		t.Fatalf("secret %q is not 160 bits of base32: %v", secret, err)
+	}
+
+	now := time.Now()
+	code := totpCode(key, now.Unix()/int64(TOTPPeriod.Seconds()))
+	if _, valid := ValidateTOTP(secret, code, now); !valid {
+		t.Errorf("code %s of a generated secret is not valid", code)
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri, err := url.Parse(TOTPURI("Example App", "user@example.com", rfc6238Secret))
+	if err != nil {
+		t.Fatalf("TOTPURI() is not a URL: %v", err)
+	}
+
+	if uri.Scheme != "otpauth" || uri.Host != "totp" || uri.Path != "/Example App:user@example.com" {
+		t.Errorf("TOTPURI() = %s", uri)
+	}
+	query := uri.Query()
+	want := map[string]string{"secret": rfc6238Secret, "issuer": "Example App", "algorithm": "SHA1", "digits": "6", "period": "30"}
+	for key, value := range want {
+		if query.Get(key) != value {
+			t.Errorf("%s = %q, want %q", key, query.Get(key), value)
+		}
+	}
+}
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	if err != nil {
+		t.Fatalf("GenerateRecoveryCodes() error = %v", err)
+	}
+	if len(codes) != 10 {
+		t.Fatalf("GenerateRecoveryCodes() returned %d codes, want 10", len(codes))
+	}
+
+	format := regexp.MustCompile(`^[a-z2-9]{5}-[a-z2-9]{5}$`)
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if !format.MatchString(code) || strings.ContainsAny(code, "ilo01") {
+			t.Errorf("recovery code %q has an unexpected format", code)
+		}
+		if seen[code] {
+			t.Errorf("recovery code %q returned twice", code)
+		}
+		seen[code] = true
+	}
+}
@@ -1,155 +1,81 @@
 package utils
 
 import (
-	"assessment/config"
-	"errors"
 	"fmt"
 	"time"
-
-	"github.com/golang-jwt/jwt"
-	"golang.org/x/crypto/bcrypt"
 )
 
-// HashPassword secures a plaintext password using bcrypt.
+// HashPassword secures a plaintext password with the configured password hasher.
 func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return "", err
-	}
-
-	return string(bytes), err
+	return PasswordService().Hash(password)
 }
 
-// Constants for JWT expiration times and secret key.
+// Constants for JWT expiration times.
 const (
 	AccessTokenExpiry  = time.Hour * 1
 	RefreshTokenExpiry = time.Hour * 72
-	SecretKey          = "secret_key"
+	InviteTokenExpiry  = time.Hour * 24 * 7
+
+	EmailVerificationExpiry = time.Hour * 24
+	PasswordResetExpiry     = time.Hour * 1
+	MFAChallengeExpiry      = time.Minute * 5
+	MagicLinkExpiry         = time.Minute * 15
+	AuthorizationCodeExpiry = time.Minute * 1
+	IDTokenExpiry           = time.Hour * 1
+	OIDCLoginExpiry         = time.Minute * 10
+	SAMLRequestExpiry       = time.Minute * 10
 )
 
-// Claims holds the standard JWT claims plus additional custom fields.
-type Claims struct {
-	Username string `json:"username"`
-	jwt.StandardClaims
-}
-
-// GenerateTokens creates JWT access and refresh tokens for a user.
-func GenerateTokens(username, email string) (accessToken string, refreshToken string, err error) {
-	// Define the claims of the access token.
-	accessClaims := jwt.MapClaims{
-		"username": username,
-		"email":    email,
-		"exp":      time.Now().Add(AccessTokenExpiry).Unix(),
-	}
-
-	// Define the claims of the refresh token.
-	refreshClaims := jwt.MapClaims{
-		"username": username,
-		"email":    email,
-		"exp":      time.Now().Add(RefreshTokenExpiry).Unix(),
-	}
-
-	// Create the access token object.
-	accessTokenObj := jwt.NewWithClaims(jwt.SigningMethodHS256, accessClaims)
-
-	// Create the refresh token object.
-	refreshTokenObj := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims)
-
-	// Sign the access token with the secret key.
-	accessToken, err = accessTokenObj.SignedString([]byte(SecretKey))
+// GenerateTokens creates JWT access and refresh tokens for a session of a user.
+// The refresh token belongs to the given family, see StartRefreshFamily.
+func GenerateTokens(userID, email, sessionID, familyID string) (accessToken string, refreshToken string, err error) {
+	// Sign the access token.
+	accessToken, err = Tokens().IssueAccessToken(userID, email, sessionID)
 	if err != nil {
 		return "", "", err
 	}
 
-	// Sign the refresh token with the secret key.
-	refreshToken, err = refreshTokenObj.SignedString([]byte(SecretKey))
+	// Sign the refresh token.
+	refreshToken, tokenID, err := Tokens().IssueRefreshToken(userID, email, sessionID, familyID)
 	if err != nil {
 		return "", "", err
 	}
 
-	// Store the refresh token in Redis for later validation.
-	err = config.Init_redis().Set(refreshToken, username, time.Hour*72).Err()
+	// Store the refresh token in Redis so it can be consumed exactly once.
+	err = storeRefreshToken(userID, tokenID, familyID)
 	if err != nil {
 		return "", "", fmt.Errorf("failed to store refresh token in Redis: %w", err)
 	}
 	return accessToken, refreshToken, nil
 }
 
-// VerifyRefreshToken checks the validity of a refresh token and returns the username and email.
-func VerifyRefreshToken(refreshToken string) (string, string, error) {
-	// Parse the refresh token.
-	token, err := jwt.Parse(refreshToken, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(SecretKey), nil
-	})
-
-	// Handle parsing errors.
+// GenerateOAuthTokens creates the access and refresh tokens of an OAuth client acting for a user
+// within the granted scopes. Refresh tokens rotate in their family just like those of GenerateTokens.
+func GenerateOAuthTokens(userID, email, sessionID, familyID, clientID string, scopes []string) (accessToken string, refreshToken string, err error) {
+	accessToken, err = Tokens().IssueOAuthAccessToken(userID, email, sessionID, clientID, scopes)
 	if err != nil {
 		return "", "", err
 	}
 
-	// Validate the token claims.
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		username, ok1 := claims["username"].(string)
-		email, ok2 := claims["email"].(string)
-		if !ok1 || !ok2 {
-			return "", "", errors.New("invalid claims")
-		}
-		return username, email, nil
-	} else {
-		return "", "", errors.New("invalid refresh token")
+	refreshToken, tokenID, err := Tokens().IssueOAuthRefreshToken(userID, email, sessionID, familyID, clientID, scopes)
+	if err != nil {
+		return "", "", err
 	}
-}
 
-// ValidateToken parses and validates a JWT token string.
-func ValidateToken(tokenString string) (*Claims, error) {
-	// Parse the token with the custom claims structure.
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(SecretKey), nil
-	})
+	err = storeRefreshToken(userID, tokenID, familyID)
 	if err != nil {
-		return nil, err
-	}
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-		return claims, nil
+		return "", "", fmt.Errorf("failed to store refresh token in Redis: %w", err)
 	}
-	return nil, err
+	return accessToken, refreshToken, nil
 }
 
-// CheckPasswordHash compares a plaintext password with a bcrypt hash.
+// CheckPasswordHash compares a plaintext password with a hash made by any supported algorithm.
 func CheckPasswordHash(password, hash string) (bool, error) {
-	// Compare the hashed password with the plaintext password.
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-	if err != nil {
-		if err == bcrypt.ErrMismatchedHashAndPassword {
-			// Password does not match the hash
-			return false, nil
-		}
-		// An unexpected error occurred
-		return false, err
-	}
-	// Passwords match
-	return true, nil
+	return PasswordService().Verify(password, hash)
 }
 
-// GetEmailFromToken extracts the email claim from a JWT token string.
-func GetEmailFromToken(tokenString string) (string, error) {
-	// Parse the token to retrieve the claims.
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return []byte(SecretKey), nil
-	})
-	if err != nil {
-		return "", err
-	}
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		email, ok := claims["email"].(string)
-		if !ok {
-			return "", errors.New("invalid email claim")
-		}
-		return email, nil
-	} else {
-		return "", errors.New("invalid token")
-	}
+// PasswordNeedsRehash reports whether a stored hash should be replaced by one made with the
+// configured algorithm and parameters, which is done when the user next signs in.
+func PasswordNeedsRehash(hash string) bool {
+	return PasswordService().NeedsRehash(hash)
 }
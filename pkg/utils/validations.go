@@ -10,7 +10,11 @@ func ValidateUser(user models.User) error {
 		return err
 	}
 
-	if err := ValidatePassword(user.Password); err != nil {
+	if err := ValidateEmail(user.Email); err != nil {
+		return err
+	}
+
+	if err := ValidatePassword(user.Password, user.Name, user.Email); err != nil {
 		return err
 	}
 
@@ -25,13 +29,26 @@ func ValidateUsername(username string) error {
 	return nil
 }
 
-func ValidatePassword(password string) error {
-	if password == "" {
-		return errors.New("Password is required")
+// ValidateEmail checks that an email address is a valid RFC 5322 addr-spec and, when
+// configured, not from a disposable email provider.
+func ValidateEmail(email string) error {
+	if email == "" {
+		return errors.New("Email is required")
 	}
-	if len(password) < 8 {
-		return errors.New("Password must be at least 8 characters long")
+	normalized, err := NormalizeEmail(email)
+	if err != nil {
+		return err
+	}
+	if IsDisposableEmail(normalized) {
+		return errors.New("Email addresses from disposable providers are not allowed")
 	}
 
 	return nil
 }
+
+// ValidatePassword checks a password against the configured password policy. personalInfo holds
+// values the password must not contain, such as the user's name and email address. A failing
+// password yields a *PasswordPolicyError listing every rule it breaks.
+func ValidatePassword(password string, personalInfo ...string) error {
+	return PasswordService().Validate(password, personalInfo...)
+}
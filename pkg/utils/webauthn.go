@@ -0,0 +1,396 @@
+package utils
+
+import (
+	"assessment/config"
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"time"
+)
+
+// WebAuthnChallengeExpiry is how long a registration or login ceremony may take.
+const WebAuthnChallengeExpiry = time.Minute * 5
+
+// WebAuthn ceremonies a challenge is issued for.
+const (
+	WebAuthnCeremonyRegistration = "registration"
+	WebAuthnCeremonyLogin        = "login"
+)
+
+// COSE algorithm identifiers of the supported credential keys.
+const (
+	COSEAlgES256 int64 = -7
+	COSEAlgEdDSA int64 = -8
+	COSEAlgRS256 int64 = -257
+)
+
+// WebAuthnAlgorithms lists the supported algorithms in order of preference.
+var WebAuthnAlgorithms = []int64{COSEAlgES256, COSEAlgEdDSA, COSEAlgRS256}
+
+// Flags of the authenticator data.
+const (
+	authenticatorFlagUserPresent      = 0x01
+	authenticatorFlagUserVerified     = 0x04
+	authenticatorFlagAttestedCredData = 0x40
+)
+
+// webauthnChallengeKeyPrefix prefixes the Redis keys holding pending ceremonies by challenge.
+const webauthnChallengeKeyPrefix = "webauthn_challenge:"
+
+// ErrWebAuthnChallengeNotFound is returned when a challenge is unknown, expired or already used.
+var ErrWebAuthnChallengeNotFound = errors.New("webauthn challenge not found or expired")
+
+// WebAuthnRelyingParty identifies this application to authenticators.
+type WebAuthnRelyingParty struct {
+	ID      string
+	Name    string
+	Origins []string
+}
+
+// WebAuthnChallenge is the server side state of a ceremony in progress.
+type WebAuthnChallenge struct {
+	Challenge string `json:"challenge"`
+	Ceremony  string `json:"ceremony"`
+	// UserId is the user the ceremony is bound to; empty for a login without a known user.
+	UserId string `json:"user_id,omitempty"`
+}
+
+// ClientData is the client data collected by the browser during a ceremony.
+type ClientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+// AuthenticatorData is the parsed authenticator data of a ceremony. The credential ID and
+// public key are only present during registration.
+type AuthenticatorData struct {
+	RPIDHash     []byte
+	Flags        byte
+	SignCount    uint32
+	CredentialId []byte
+	PublicKey    []byte
+}
+
+// UserVerified reports whether the authenticator verified the user, e.g. by PIN or biometrics.
+func (data *AuthenticatorData) UserVerified() bool {
+	return data.Flags&authenticatorFlagUserVerified != 0
+}
+
+// WebAuthnRP returns the relying party from the configuration, derived from the base URL when not set.
+func WebAuthnRP() WebAuthnRelyingParty {
+	appConfig := config.GetAppConfig()
+	rp := WebAuthnRelyingParty{
+		ID:      appConfig.WebAuthn.RPID,
+		Name:    appConfig.WebAuthn.RPName,
+		Origins: appConfig.WebAuthn.Origins,
+	}
+
+	if baseURL, err := url.Parse(appConfig.BaseURL); err == nil {
+		if rp.ID == "" {
+			rp.ID = baseURL.Hostname()
+		}
+		if len(rp.Origins) == 0 {
+			rp.Origins = []string{baseURL.Scheme + "://" + baseURL.Host}
+		}
+	}
+	if rp.Name == "" {
+		rp.Name = appConfig.AppName
+	}
+
+	return rp
+}
+
+// VerifyRegistration checks the response of a registration ceremony against the expected challenge
+// and returns the authenticator data carrying the new credential. Only the "none" attestation
+// format is accepted, since the authenticator model is not used to make trust decisions.
+func (rp WebAuthnRelyingParty) VerifyRegistration(challenge string, clientDataJSON, attestationObject []byte) (*AuthenticatorData, error) {
+	if err := rp.verifyClientData(clientDataJSON, "webauthn.create", challenge); err != nil {
+		return nil, err
+	}
+
+	decoded, rest, err := decodeCBOR(attestationObject)
+	if err != nil {
+		return nil, fmt.Errorf("invalid attestation object: %w", err)
+	}
+	attestation, ok := decoded.(map[interface{}]interface{})
+	if !ok || len(rest) != 0 {
+		return nil, errors.New("invalid attestation object")
+	}
+	if format, _ := attestation["fmt"].(string); format != "none" {
+		return nil, fmt.Errorf("unsupported attestation format %q", format)
+	}
+	rawAuthData, ok := attestation["authData"].([]byte)
+	if !ok {
+		return nil, errors.New("attestation object is missing authenticator data")
+	}
+
+	authData, err := ParseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+	if err := rp.verifyAuthenticatorData(authData); err != nil {
+		return nil, err
+	}
+	if authData.CredentialId == nil {
+		return nil, errors.New("authenticator data is missing the credential")
+	}
+	if _, _, err := parseCOSEKey(authData.PublicKey); err != nil {
+		return nil, err
+	}
+
+	return authData, nil
+}
+
+// VerifyAssertion checks the response of a login ceremony against the expected challenge and the
+// stored public key of the credential, and returns the authenticator data.
+func (rp WebAuthnRelyingParty) VerifyAssertion(challenge string, publicKey, clientDataJSON, rawAuthData, signature []byte) (*AuthenticatorData, error) {
+	if err := rp.verifyClientData(clientDataJSON, "webauthn.get", challenge); err != nil {
+		return nil, err
+	}
+
+	authData, err := ParseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+	if err := rp.verifyAuthenticatorData(authData); err != nil {
+		return nil, err
+	}
+
+	// The signature covers the authenticator data followed by the hash of the client data.
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(append([]byte(nil), rawAuthData...), clientDataHash[:]...)
+
+	key, algorithm, err := parseCOSEKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	if !verifyCOSESignature(key, algorithm, signed, signature) {
+		return nil, errors.New("invalid signature")
+	}
+
+	return authData, nil
+}
+
+// verifyClientData checks the ceremony type, challenge and origin of the client data.
+func (rp WebAuthnRelyingParty) verifyClientData(clientDataJSON []byte, ceremonyType, challenge string) error {
+	var clientData ClientData
+	if err := json.Unmarshal(clientDataJSON, &clientData); err != nil {
+		return fmt.Errorf("invalid client data: %w", err)
+	}
+
+	if clientData.Type != ceremonyType {
+		return fmt.Errorf("unexpected ceremony type %q", clientData.Type)
+	}
+	if clientData.Challenge != challenge {
+		return errors.New("challenge mismatch")
+	}
+	if clientData.CrossOrigin || !rp.allowsOrigin(clientData.Origin) {
+		return fmt.Errorf("unexpected origin %q", clientData.Origin)
+	}
+
+	return nil
+}
+
+// allowsOrigin reports whether ceremonies may be performed from the origin.
+func (rp WebAuthnRelyingParty) allowsOrigin(origin string) bool {
+	for _, allowed := range rp.Origins {
+		if origin == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyAuthenticatorData checks that the authenticator data is scoped to this relying party
+// and that the user was present.
+func (rp WebAuthnRelyingParty) verifyAuthenticatorData(authData *AuthenticatorData) error {
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+	if !bytes.Equal(authData.RPIDHash, rpIDHash[:]) {
+		return errors.New("credential is scoped to another relying party")
+	}
+	if authData.Flags&authenticatorFlagUserPresent == 0 {
+		return errors.New("user presence is required")
+	}
+
+	return nil
+}
+
+// ParseAuthenticatorData parses the binary authenticator data of a registration or assertion.
+func ParseAuthenticatorData(data []byte) (*AuthenticatorData, error) {
+	if len(data) < 37 {
+		return nil, errors.New("authenticator data is too short")
+	}
+
+	authData := &AuthenticatorData{
+		RPIDHash:  data[:32],
+		Flags:     data[32],
+		SignCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+	rest := data[37:]
+
+	if authData.Flags&authenticatorFlagAttestedCredData != 0 {
+		// AAGUID (16 bytes), credential ID length (2 bytes), credential ID, COSE public key.
+		if len(rest) < 18 {
+			return nil, errors.New("attested credential data is too short")
+		}
+		idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if idLength > 1023 || len(rest) < idLength {
+			return nil, errors.New("invalid credential ID")
+		}
+		authData.CredentialId = rest[:idLength]
+		rest = rest[idLength:]
+
+		_, remaining, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, fmt.Errorf("invalid credential public key: %w", err)
+		}
+		authData.PublicKey = rest[:len(rest)-len(remaining)]
+		rest = remaining
+	}
+
+	// Extension outputs may follow; they are not used.
+	if len(rest) > 0 {
+		if _, _, err := decodeCBOR(rest); err != nil {
+			return nil, fmt.Errorf("invalid extensions: %w", err)
+		}
+	}
+
+	return authData, nil
+}
+
+// parseCOSEKey decodes a COSE_Key (RFC 9052) into a public key and its algorithm.
+func parseCOSEKey(data []byte) (interface{}, int64, error) {
+	decoded, _, err := decodeCBOR(data)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid credential public key: %w", err)
+	}
+	coseKey, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, 0, errors.New("invalid credential public key")
+	}
+
+	keyType, _ := coseKey[int64(1)].(int64)
+	algorithm, _ := coseKey[int64(3)].(int64)
+
+	switch {
+	case keyType == 2 && algorithm == COSEAlgES256:
+		curve, _ := coseKey[int64(-1)].(int64)
+		x, _ := coseKey[int64(-2)].([]byte)
+		y, _ := coseKey[int64(-3)].([]byte)
+		if curve != 1 || len(x) != 32 || len(y) != 32 {
+			return nil, 0, errors.New("invalid P-256 public key")
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, 0, errors.New("invalid P-256 public key")
+		}
+		return key, algorithm, nil
+	case keyType == 1 && algorithm == COSEAlgEdDSA:
+		curve, _ := coseKey[int64(-1)].(int64)
+		x, _ := coseKey[int64(-2)].([]byte)
+		if curve != 6 || len(x) != ed25519.PublicKeySize {
+			return nil, 0, errors.New("invalid Ed25519 public key")
+		}
+		return ed25519.PublicKey(x), algorithm, nil
+	case keyType == 3 && algorithm == COSEAlgRS256:
+		n, _ := coseKey[int64(-1)].([]byte)
+		e, _ := coseKey[int64(-2)].([]byte)
+		if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return nil, 0, errors.New("invalid RSA public key")
+		}
+		exponent := new(big.Int).SetBytes(e)
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, algorithm, nil
+	default:
+		return nil, 0, fmt.Errorf("unsupported credential key type %d with algorithm %d", keyType, algorithm)
+	}
+}
+
+// verifyCOSESignature checks a WebAuthn signature made with a credential key.
+func verifyCOSESignature(key interface{}, algorithm int64, data, signature []byte) bool {
+	switch algorithm {
+	case COSEAlgES256:
+		hash := sha256.Sum256(data)
+		return ecdsa.VerifyASN1(key.(*ecdsa.PublicKey), hash[:], signature)
+	case COSEAlgEdDSA:
+		return ed25519.Verify(key.(ed25519.PublicKey), data, signature)
+	case COSEAlgRS256:
+		hash := sha256.Sum256(data)
+		return rsa.VerifyPKCS1v15(key.(*rsa.PublicKey), crypto.SHA256, hash[:], signature) == nil
+	default:
+		return false
+	}
+}
+
+// StartWebAuthnCeremony generates a challenge for a ceremony and keeps its state in Redis
+// until the ceremony is finished or WebAuthnChallengeExpiry passes.
+func StartWebAuthnCeremony(ceremony, userID string) (string, error) {
+	challenge, err := GenerateSecureToken()
+	if err != nil {
+		return "", err
+	}
+
+	state, err := json.Marshal(WebAuthnChallenge{Challenge: challenge, Ceremony: ceremony, UserId: userID})
+	if err != nil {
+		return "", err
+	}
+
+	err = config.Init_redis().Set(webauthnChallengeKeyPrefix+challenge, state, WebAuthnChallengeExpiry).Err()
+	if err != nil {
+		return "", err
+	}
+
+	return challenge, nil
+}
+
+// ConsumeWebAuthnCeremony looks up the ceremony started for the challenge in the client data and
+// removes it, so every challenge is answered at most once.
+func ConsumeWebAuthnCeremony(clientDataJSON []byte, ceremony string) (*WebAuthnChallenge, error) {
+	var clientData ClientData
+	if err := json.Unmarshal(clientDataJSON, &clientData); err != nil || clientData.Challenge == "" {
+		return nil, ErrWebAuthnChallengeNotFound
+	}
+
+	redisClient := config.Init_redis()
+	key := webauthnChallengeKeyPrefix + clientData.Challenge
+
+	state, err := redisClient.Get(key).Bytes()
+	if err != nil {
+		return nil, ErrWebAuthnChallengeNotFound
+	}
+	deleted, err := redisClient.Del(key).Result()
+	if err != nil {
+		return nil, err
+	}
+	if deleted == 0 {
+		return nil, ErrWebAuthnChallengeNotFound
+	}
+
+	var challenge WebAuthnChallenge
+	if err := json.Unmarshal(state, &challenge); err != nil {
+		return nil, err
+	}
+	if challenge.Ceremony != ceremony {
+		return nil, ErrWebAuthnChallengeNotFound
+	}
+
+	return &challenge, nil
+}
+
+// DecodeBase64URL decodes the unpadded URL-safe base64 used for binary fields of WebAuthn JSON.
+func DecodeBase64URL(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(value)
+}
@@ -0,0 +1,308 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"math/big"
+	"testing"
+)
+
+const (
+	testRPID   = "example.com"
+	testOrigin = "https://example.com"
+)
+
+var testRP = WebAuthnRelyingParty{ID: testRPID, Name: "Example", Origins: []string{testOrigin}}
+
+var coseAlgorithmNames = map[int64]string{
+	COSEAlgES256: "ES256",
+	COSEAlgEdDSA: "EdDSA",
+	COSEAlgRS256: "RS256",
+}
+
+// fakeAuthenticator is a software authenticator that registers a credential and signs assertions
+// the way a browser and security key would.
+type fakeAuthenticator struct {
+	rpID         string
+	algorithm    int64
+	credentialId []byte
+	signer       crypto.Signer
+	signCount    uint32
+}
+
+func newFakeAuthenticator(t *testing.T, algorithm int64) *fakeAuthenticator {
+	t.Helper()
+
+	var signer crypto.Signer
+	var err error
+	switch algorithm {
+	case COSEAlgES256:
+		signer, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case COSEAlgEdDSA:
+		_, signer, err = ed25519.GenerateKey(rand.Reader)
+	case COSEAlgRS256:
+		signer, err = rsa.GenerateKey(rand.Reader, 2048)
+	}
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+
+	return &fakeAuthenticator{
+		rpID:         testRPID,
+		algorithm:    algorithm,
+		credentialId: []byte("credential-" + t.Name()),
+		signer:       signer,
+	}
+}
+
+// coseKey encodes the public key of the credential as a COSE_Key.
+func (a *fakeAuthenticator) coseKey() []byte {
+	switch key := a.signer.Public().(type) {
+	case *ecdsa.PublicKey:
+		return encodeTestCBOR(cborMap{
+			{int64(1), int64(2)},
+			{int64(3), COSEAlgES256},
+			{int64(-1), int64(1)},
+			{int64(-2), key.X.FillBytes(make([]byte, 32))},
+			{int64(-3), key.Y.FillBytes(make([]byte, 32))},
+		})
+	case ed25519.PublicKey:
+		return encodeTestCBOR(cborMap{
+			{int64(1), int64(1)},
+			{int64(3), COSEAlgEdDSA},
+			{int64(-1), int64(6)},
+			{int64(-2), []byte(key)},
+		})
+	case *rsa.PublicKey:
+		return encodeTestCBOR(cborMap{
+			{int64(1), int64(3)},
+			{int64(3), COSEAlgRS256},
+			{int64(-1), key.N.Bytes()},
+			{int64(-2), big.NewInt(int64(key.E)).Bytes()},
+		})
+	}
+	return nil
+}
+
+// authenticatorData builds authenticator data with the flags, carrying the credential when attested.
+func (a *fakeAuthenticator) authenticatorData(flags byte, attested bool) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.rpID))
+	data := append([]byte(nil), rpIDHash[:]...)
+	if attested {
+		flags |= authenticatorFlagAttestedCredData
+	}
+	data = append(data, flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+
+	if attested {
+		data = append(data, make([]byte, 16)...)
+		data = binary.BigEndian.AppendUint16(data, uint16(len(a.credentialId)))
+		data = append(data, a.credentialId...)
+		data = append(data, a.coseKey()...)
+	}
+	return data
+}
+
+// register answers a registration ceremony with the "none" attestation format.
+func (a *fakeAuthenticator) register(t *testing.T, clientData ClientData, flags byte, format string) (clientDataJSON, attestationObject []byte) {
+	t.Helper()
+
+	attestationObject = encodeTestCBOR(cborMap{
+		{"fmt", format},
+		{"attStmt", cborMap{}},
+		{"authData", a.authenticatorData(flags, true)},
+	})
+	return marshalClientData(t, clientData), attestationObject
+}
+
+// assert answers a login ceremony by signing the authenticator data and the client data hash.
+func (a *fakeAuthenticator) assert(t *testing.T, clientData ClientData, flags byte) (clientDataJSON, authData, signature []byte) {
+	t.Helper()
+
+	a.signCount++
+	clientDataJSON = marshalClientData(t, clientData)
+	authData = a.authenticatorData(flags, false)
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(append([]byte(nil), authData...), clientDataHash[:]...)
+
+	var err error
+	if a.algorithm == COSEAlgEdDSA {
+		signature, err = a.signer.Sign(rand.Reader, signed, crypto.Hash(0))
+	} else {
+		digest := sha256.Sum256(signed)
+		signature, err = a.signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+	if err != nil {
+		t.Fatalf("sign assertion: %v", err)
+	}
+	return clientDataJSON, authData, signature
+}
+
+func marshalClientData(t *testing.T, clientData ClientData) []byte {
+	t.Helper()
+
+	data, err := json.Marshal(clientData)
+	if err != nil {
+		t.Fatalf("marshal client data: %v", err)
+	}
+	return data
+}
+
+func TestVerifyRegistration(t *testing.T) {
+	const challenge = "registration-challenge"
+	created := ClientData{Type: "webauthn.create", Challenge: challenge, Origin: testOrigin}
+
+	tests := []struct {
+		name       string
+		clientData ClientData
+		rpID       string
+		flags      byte
+		format     string
+		wantErr    bool
+	}{
+		{name: "valid", clientData: created, flags: authenticatorFlagUserPresent, format: "none"},
+		{name: "user verified", clientData: created, flags: authenticatorFlagUserPresent | authenticatorFlagUserVerified, format: "none"},
+		{name: "login ceremony", clientData: ClientData{Type: "webauthn.get", Challenge: challenge, Origin: testOrigin}, flags: authenticatorFlagUserPresent, format: "none", wantErr: true},
+		{name: "other challenge", clientData: ClientData{Type: "webauthn.create", Challenge: "other", Origin: testOrigin}, flags: authenticatorFlagUserPresent, format: "none", wantErr: true},
+		{name: "other origin", clientData: ClientData{Type: "webauthn.create", Challenge: challenge, Origin: "https://evil.example"}, flags: authenticatorFlagUserPresent, format: "none", wantErr: true},
+		{name: "cross origin", clientData: ClientData{Type: "webauthn.create", Challenge: challenge, Origin: testOrigin, CrossOrigin: true}, flags: authenticatorFlagUserPresent, format: "none", wantErr: true},
+		{name: "other relying party", clientData: created, rpID: "evil.example", flags: authenticatorFlagUserPresent, format: "none", wantErr: true},
+		{name: "user not present", clientData: created, format: "none", wantErr: true},
+		{name: "attestation format", clientData: created, flags: authenticatorFlagUserPresent, format: "packed", wantErr: true},
+	}
+
+	for _, algorithm := range WebAuthnAlgorithms {
+		for _, tt := range tests {
+			t.Run(coseAlgorithmNames[algorithm]+"/"+tt.name, func(t *testing.T) {
+				authenticator := newFakeAuthenticator(t, algorithm)
+				if tt.rpID != "" {
+					authenticator.rpID = tt.rpID
+				}
+				clientDataJSON, attestationObject := authenticator.register(t, tt.clientData, tt.flags, tt.format)
+
+				authData, err := testRP.VerifyRegistration(challenge, clientDataJSON, attestationObject)
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("VerifyRegistration() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				if err != nil {
+					return
+				}
+				if string(authData.CredentialId) != string(authenticator.credentialId) {
+					t.Errorf("CredentialId = %q, want %q", authData.CredentialId, authenticator.credentialId)
+				}
+				if string(authData.PublicKey) != string(authenticator.coseKey()) {
+					t.Errorf("PublicKey does not match the credential key")
+				}
+				if authData.UserVerified() != (tt.flags&authenticatorFlagUserVerified != 0) {
+					t.Errorf("UserVerified() = %v", authData.UserVerified())
+				}
+			})
+		}
+	}
+}
+
+func TestVerifyRegistrationMalformed(t *testing.T) {
+	const challenge = "registration-challenge"
+	clientDataJSON := marshalClientData(t, ClientData{Type: "webauthn.create", Challenge: challenge, Origin: testOrigin})
+	authenticator := newFakeAuthenticator(t, COSEAlgES256)
+
+	withoutCredential := encodeTestCBOR(cborMap{
+		{"fmt", "none"},
+		{"attStmt", cborMap{}},
+		{"authData", authenticator.authenticatorData(authenticatorFlagUserPresent, false)},
+	})
+	unsupportedKey := authenticator.authenticatorData(authenticatorFlagUserPresent, true)
+	unsupportedKey = unsupportedKey[:len(unsupportedKey)-len(authenticator.coseKey())]
+	unsupportedKey = append(unsupportedKey, encodeTestCBOR(cborMap{{int64(1), int64(2)}, {int64(3), int64(-36)}})...)
+
+	tests := []struct {
+		name              string
+		clientDataJSON    []byte
+		attestationObject []byte
+	}{
+		{name: "client data is not JSON", clientDataJSON: []byte("{"), attestationObject: withoutCredential},
+		{name: "attestation is not CBOR", clientDataJSON: clientDataJSON, attestationObject: []byte{0xff}},
+		{name: "attestation is not a map", clientDataJSON: clientDataJSON, attestationObject: encodeTestCBOR("none")},
+		{name: "trailing bytes", clientDataJSON: clientDataJSON, attestationObject: append(append([]byte(nil), withoutCredential...), 0x00)},
+		{name: "missing authenticator data", clientDataJSON: clientDataJSON, attestationObject: encodeTestCBOR(cborMap{{"fmt", "none"}})},
+		{name: "missing credential", clientDataJSON: clientDataJSON, attestationObject: withoutCredential},
+		{name: "unsupported key", clientDataJSON: clientDataJSON, attestationObject: encodeTestCBOR(cborMap{{"fmt", "none"}, {"authData", unsupportedKey}})},
+		{name: "short authenticator data", clientDataJSON: clientDataJSON, attestationObject: encodeTestCBOR(cborMap{{"fmt", "none"}, {"authData", make([]byte, 36)}})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := testRP.VerifyRegistration(challenge, tt.clientDataJSON, tt.attestationObject); err == nil {
+				t.Fatal("VerifyRegistration() succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestVerifyAssertion(t *testing.T) {
+	const challenge = "login-challenge"
+	requested := ClientData{Type: "webauthn.get", Challenge: challenge, Origin: testOrigin}
+
+	tests := []struct {
+		name       string
+		clientData ClientData
+		rpID       string
+		flags      byte
+		tamper     func(authData, signature []byte)
+		otherKey   bool
+		wantErr    bool
+	}{
+		{name: "valid", clientData: requested, flags: authenticatorFlagUserPresent},
+		{name: "user verified", clientData: requested, flags: authenticatorFlagUserPresent | authenticatorFlagUserVerified},
+		{name: "registration ceremony", clientData: ClientData{Type: "webauthn.create", Challenge: challenge, Origin: testOrigin}, flags: authenticatorFlagUserPresent, wantErr: true},
+		{name: "other challenge", clientData: ClientData{Type: "webauthn.get", Challenge: "other", Origin: testOrigin}, flags: authenticatorFlagUserPresent, wantErr: true},
+		{name: "other origin", clientData: ClientData{Type: "webauthn.get", Challenge: challenge, Origin: "https://evil.example"}, flags: authenticatorFlagUserPresent, wantErr: true},
+		{name: "other relying party", clientData: requested, rpID: "evil.example", flags: authenticatorFlagUserPresent, wantErr: true},
+		{name: "user not present", clientData: requested, wantErr: true},
+		{name: "tampered sign count", clientData: requested, flags: authenticatorFlagUserPresent, tamper: func(authData, _ []byte) { authData[36]++ }, wantErr: true},
+		{name: "tampered signature", clientData: requested, flags: authenticatorFlagUserPresent, tamper: func(_, signature []byte) { signature[len(signature)-1]++ }, wantErr: true},
+		{name: "signed by another key", clientData: requested, flags: authenticatorFlagUserPresent, otherKey: true, wantErr: true},
+	}
+
+	for _, algorithm := range WebAuthnAlgorithms {
+		for _, tt := range tests {
+			t.Run(coseAlgorithmNames[algorithm]+"/"+tt.name, func(t *testing.T) {
+				authenticator := newFakeAuthenticator(t, algorithm)
+				publicKey := authenticator.coseKey()
+				if tt.rpID != "" {
+					authenticator.rpID = tt.rpID
+				}
+				if tt.otherKey {
+					authenticator = newFakeAuthenticator(t, algorithm)
+				}
+
+				clientDataJSON, authData, signature := authenticator.assert(t, tt.clientData, tt.flags)
+				if tt.tamper != nil {
+					tt.tamper(authData, signature)
+				}
+
+				parsed, err := testRP.VerifyAssertion(challenge, publicKey, clientDataJSON, authData, signature)
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("VerifyAssertion() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				if err != nil {
+					return
+				}
+				if parsed.SignCount != authenticator.signCount {
+					t.Errorf("SignCount = %d, want %d", parsed.SignCount, authenticator.signCount)
+				}
+				if parsed.UserVerified() != (tt.flags&authenticatorFlagUserVerified != 0) {
+					t.Errorf("UserVerified() = %v", parsed.UserVerified())
+				}
+			})
+		}
+	}
+}